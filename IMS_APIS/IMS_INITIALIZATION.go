@@ -3,7 +3,10 @@ package IMS_APIS
 import (
 	"context"
 	"errors"
+	"fmt"
+	nethttp "net/http"
 
+	"github.com/RohitGupta-omniful/OMS/internal/breaker"
 	"github.com/omniful/go_commons/config"
 	"github.com/omniful/go_commons/http"
 	"github.com/omniful/go_commons/i18n"
@@ -11,77 +14,220 @@ import (
 	"github.com/omniful/go_commons/log"
 )
 
-var imsClient *interservice_client.Client
-var authToken string
+// IMSClient is the set of IMS calls OMS depends on. Every answer is
+// classified so callers can tell an invalid ID from an IMS outage.
+type IMSClient interface {
+	ValidateHub(ctx context.Context, hubID string) ValidationResult
+	ValidateSKUOnHub(ctx context.Context, hubID string, skuID string) ValidationResult
+	ValidateBulk(ctx context.Context, hubIDs []string, pairs []HubSKU) (BulkValidationResult, error)
+	ListHubs(ctx context.Context, tenantID int) ([]Hub, error)
+}
+
+// Client is the interservice implementation of IMSClient. Calls go through
+// an optional circuit breaker.
+type Client struct {
+	client    *interservice_client.Client
+	authToken string
+	breaker   *breaker.Breaker
+}
 
-func InitIMSClient(ctx context.Context) error {
+// NewIMSClient connects to IMS using the interservice_client config section.
+func NewIMSClient(ctx context.Context, cb *breaker.Breaker) (*Client, error) {
 	configIMS := interservice_client.Config{
 		ServiceName: config.GetString(ctx, "interservice_client.serviceName"),
 		BaseURL:     config.GetString(ctx, "interservice_client.baseURL"),
 		Timeout:     config.GetDuration(ctx, "interservice_client.timeout"),
 	}
 
+	authToken := config.GetString(ctx, "interservice_client.auth_token")
+	if authToken == "" {
+		return nil, errors.New(i18n.Translate(ctx, "IMS auth token is not set"))
+	}
+
 	client, err := interservice_client.NewClientWithConfig(configIMS)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	authToken = "my-secret-token"
-	if authToken == "" {
-		return errors.New(i18n.Translate(ctx, "IMS auth token is not set"))
+	log.Infof(i18n.Translate(ctx, "Connected to INTER_SERVICE Client"))
+	return &Client{client: client, authToken: authToken, breaker: cb}, nil
+}
+
+type ValidationResponse struct {
+	IsValid bool `json:"is_valid"`
+}
+
+// SKUAvailability is IMS's answer for a SKU at a particular hub.
+// AvailableQty is only set when IMS reports stock levels.
+type SKUAvailability struct {
+	Valid        bool `json:"is_valid"`
+	AvailableQty *int `json:"available_quantity,omitempty"`
+}
+
+// ValidateHub asks IMS whether hubID is a valid hub.
+func (c *Client) ValidateHub(ctx context.Context, hubID string) ValidationResult {
+	var resp ValidationResponse
+	if err := c.get(ctx, "/validate/hub/"+hubID, &resp); err != nil {
+		if !errors.Is(err, breaker.ErrOpen) {
+			log.Errorf(i18n.Translate(ctx, "Error validating hubID %s: %v"), hubID, err)
+		}
+		return errorResult(err)
 	}
+	return boolResult(resp.IsValid, nil)
+}
 
-	log.Infof(i18n.Translate(ctx, "Connected to INTER_SERVICE Client"))
-	SetIMSClient(client)
-	return nil
+// ValidateSKUOnHub asks IMS whether skuID is stocked at hubID.
+func (c *Client) ValidateSKUOnHub(ctx context.Context, hubID string, skuID string) ValidationResult {
+	var resp SKUAvailability
+	if err := c.get(ctx, "/validate/hub/"+hubID+"/sku/"+skuID, &resp); err != nil {
+		if !errors.Is(err, breaker.ErrOpen) {
+			log.Errorf(i18n.Translate(ctx, "Error validating SKU %s on hub %s: %v"), skuID, hubID, err)
+		}
+		return errorResult(err)
+	}
+	return boolResult(resp.Valid, resp.AvailableQty)
 }
 
-func SetIMSClient(client *interservice_client.Client) {
-	imsClient = client
+// Hub is an IMS hub. Latitude and Longitude are nil when IMS has no
+// location for the hub.
+type Hub struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
 }
 
-type ValidationResponse struct {
-	IsValid bool `json:"is_valid"`
+type listHubsResponse struct {
+	Hubs []Hub `json:"hubs"`
 }
 
-func ValidateHub(ctx context.Context, hubID string) bool {
-	if imsClient == nil {
-		log.Error(i18n.Translate(ctx, "IMS client is not initialized"))
-		return false
+// ListHubs returns the hubs IMS has for a tenant.
+func (c *Client) ListHubs(ctx context.Context, tenantID int) ([]Hub, error) {
+	var resp listHubsResponse
+	if err := c.get(ctx, fmt.Sprintf("/tenants/%d/hubs", tenantID), &resp); err != nil {
+		if !errors.Is(err, breaker.ErrOpen) {
+			log.Errorf(i18n.Translate(ctx, "Error listing hubs for tenant %d: %v"), tenantID, err)
+		}
+		return nil, err
 	}
+	return resp.Hubs, nil
+}
 
+type bulkValidationRequest struct {
+	HubIDs  []string `json:"hub_ids"`
+	HubSKUs []HubSKU `json:"hub_skus"`
+}
+
+type bulkValidationResponse struct {
+	Hubs    map[string]bool `json:"hubs"`
+	HubSKUs []struct {
+		HubSKU
+		SKUAvailability
+	} `json:"hub_skus"`
+}
+
+// ValidateBulk validates hubs and (hub, SKU) pairs in a single IMS call.
+func (c *Client) ValidateBulk(ctx context.Context, hubIDs []string, pairs []HubSKU) (BulkValidationResult, error) {
 	req := &http.Request{
-		Url:     "/validate/hub/" + hubID,
-		Headers: map[string][]string{"Authorization": {"Bearer " + authToken}},
+		Url:     "/validate/bulk",
+		Body:    bulkValidationRequest{HubIDs: hubIDs, HubSKUs: pairs},
+		Headers: c.headers(),
 	}
 
-	var result ValidationResponse
-	_, err := imsClient.Get(req, &result)
+	var resp bulkValidationResponse
+	err := c.breaker.Do(ctx, func() error {
+		if _, ierr := c.client.Post(req, &resp); ierr != nil {
+			return &RequestError{URL: req.Url, StatusCode: int(ierr.StatusCode), Message: ierr.Error()}
+		}
+		return nil
+	}, IsTransient)
 	if err != nil {
-		log.Errorf(i18n.Translate(ctx, "Error validating hubID %s: %v"), hubID, err)
-		return false
+		return BulkValidationResult{}, err
 	}
 
-	return result.IsValid
+	result := BulkValidationResult{
+		Hubs:    make(map[string]ValidationResult, len(resp.Hubs)),
+		HubSKUs: make(map[HubSKU]ValidationResult, len(resp.HubSKUs)),
+	}
+	for id, valid := range resp.Hubs {
+		result.Hubs[id] = boolResult(valid, nil)
+	}
+	for _, r := range resp.HubSKUs {
+		result.HubSKUs[r.HubSKU] = boolResult(r.Valid, r.AvailableQty)
+	}
+	return result, nil
 }
 
-func ValidateSKUOnHub(ctx context.Context, skuID string) bool {
-	if imsClient == nil {
-		log.Error(i18n.Translate(ctx, "IMS client is not initialized"))
-		return false
+func (c *Client) get(ctx context.Context, url string, result interface{}) error {
+	if c.client == nil {
+		return &RequestError{URL: url, Message: i18n.Translate(ctx, "IMS client is not initialized")}
 	}
 
 	req := &http.Request{
-		Url:     "/validate/sku/" + skuID,
-		Headers: map[string][]string{"Authorization": {"Bearer " + authToken}},
+		Url:     url,
+		Headers: c.headers(),
 	}
 
-	var result ValidationResponse
-	_, err := imsClient.Get(req, &result)
-	if err != nil {
-		log.Errorf(i18n.Translate(ctx, "Error validating SKU %s: %v"), skuID, err)
-		return false
+	return c.breaker.Do(ctx, func() error {
+		if _, ierr := c.client.Get(req, result); ierr != nil {
+			return &RequestError{URL: url, StatusCode: int(ierr.StatusCode), Message: ierr.Error()}
+		}
+		return nil
+	}, IsTransient)
+}
+
+// IsTransient reports whether a failed IMS call should be retried rather
+// than treated as an answer. It also decides what counts against the
+// circuit breaker.
+func IsTransient(err error) bool {
+	return errorResult(err).IsTransient()
+}
+
+// IsAuthError reports whether IMS refused OMS's credentials for a failed
+// call. Such failures are permanent until the configuration is fixed.
+func IsAuthError(err error) bool {
+	return errorResult(err).IsAuthError()
+}
+
+func (c *Client) headers() map[string][]string {
+	return map[string][]string{"Authorization": {"Bearer " + c.authToken}}
+}
+
+// RequestError is a failed IMS call. StatusCode is zero when no response was
+// received, e.g. on a timeout or connection error.
+type RequestError struct {
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (e *RequestError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("IMS request %s failed: %s", e.URL, e.Message)
 	}
+	return fmt.Sprintf("IMS request %s failed with status %d: %s", e.URL, e.StatusCode, e.Message)
+}
 
-	return result.IsValid
+// errorResult classifies a failed IMS call. 404 means IMS does not know the
+// ID, 401 and 403 mean OMS's credentials were refused, and other client
+// errors mean IMS rejected the ID; timeouts, throttling and server errors are
+// transient and must not reject orders.
+func errorResult(err error) ValidationResult {
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		return ValidationResult{Status: ResultTransientError, Err: err}
+	}
+
+	switch code := reqErr.StatusCode; {
+	case code == nethttp.StatusNotFound:
+		return ValidationResult{Status: ResultNotFound, Err: err}
+	case code == nethttp.StatusUnauthorized, code == nethttp.StatusForbidden:
+		return ValidationResult{Status: ResultAuthError, Err: err}
+	case code == nethttp.StatusRequestTimeout, code == nethttp.StatusTooManyRequests:
+		return ValidationResult{Status: ResultTransientError, Err: err}
+	case code >= 400 && code < 500:
+		return ValidationResult{Status: ResultInvalid, Err: err}
+	default:
+		return ValidationResult{Status: ResultTransientError, Err: err}
+	}
 }
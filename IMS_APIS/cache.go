@@ -0,0 +1,59 @@
+package IMS_APIS
+
+import (
+	"sync"
+	"time"
+)
+
+const maxCacheEntries = 100000
+
+type cacheEntry struct {
+	result    ValidationResult
+	expiresAt time.Time
+}
+
+// ttlCache is an in-process cache of IMS validation results.
+type ttlCache struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+}
+
+func newTTLCache() *ttlCache {
+	return &ttlCache{entries: make(map[string]cacheEntry)}
+}
+
+func (c *ttlCache) Get(key string) (result ValidationResult, ok bool) {
+	c.mu.RLock()
+	entry, found := c.entries[key]
+	c.mu.RUnlock()
+	if !found || time.Now().After(entry.expiresAt) {
+		return ValidationResult{}, false
+	}
+	return entry.result, true
+}
+
+func (c *ttlCache) Set(key string, result ValidationResult, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCacheEntries {
+		c.evictExpiredLocked()
+		if len(c.entries) >= maxCacheEntries {
+			c.entries = make(map[string]cacheEntry)
+		}
+	}
+	c.entries[key] = cacheEntry{result: result, expiresAt: time.Now().Add(ttl)}
+}
+
+func (c *ttlCache) evictExpiredLocked() {
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+}
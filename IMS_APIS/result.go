@@ -0,0 +1,52 @@
+package IMS_APIS
+
+// ResultStatus classifies an IMS validation answer.
+type ResultStatus string
+
+const (
+	ResultValid          ResultStatus = "valid"
+	ResultInvalid        ResultStatus = "invalid"
+	ResultNotFound       ResultStatus = "not_found"
+	ResultTransientError ResultStatus = "transient_error"
+	// ResultAuthError means IMS rejected OMS's credentials. Retrying cannot
+	// help and the answer says nothing about the ID.
+	ResultAuthError ResultStatus = "auth_error"
+)
+
+// ValidationResult is the classified answer for a hub or a SKU at a hub.
+// AvailableQty is only set when IMS reports stock levels; Err is set for
+// not found, transient and auth error results.
+type ValidationResult struct {
+	Status       ResultStatus
+	AvailableQty *int
+	Err          error
+}
+
+// IsValid reports whether IMS confirmed the ID.
+func (r ValidationResult) IsValid() bool { return r.Status == ResultValid }
+
+// IsTransient reports whether IMS could not be reached or failed, in which
+// case the check should be retried rather than the order rejected.
+func (r ValidationResult) IsTransient() bool { return r.Status == ResultTransientError }
+
+// IsAuthError reports whether IMS refused OMS's credentials.
+func (r ValidationResult) IsAuthError() bool { return r.Status == ResultAuthError }
+
+// CanFulfil reports whether the hub stocks the SKU in at least qty units.
+// Unknown stock levels are treated as sufficient.
+func (r ValidationResult) CanFulfil(qty int) bool {
+	return r.IsValid() && (r.AvailableQty == nil || *r.AvailableQty >= qty)
+}
+
+// BulkValidationResult holds the answers of a bulk validation call.
+type BulkValidationResult struct {
+	Hubs    map[string]ValidationResult
+	HubSKUs map[HubSKU]ValidationResult
+}
+
+func boolResult(valid bool, availableQty *int) ValidationResult {
+	if !valid {
+		return ValidationResult{Status: ResultInvalid}
+	}
+	return ValidationResult{Status: ResultValid, AvailableQty: availableQty}
+}
@@ -0,0 +1,190 @@
+package IMS_APIS
+
+import (
+	"context"
+	"time"
+
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+const (
+	defaultPositiveTTL = 10 * time.Minute
+	defaultNegativeTTL = time.Minute
+	defaultQuantityTTL = 10 * time.Second
+	bulkChunkSize      = 500
+)
+
+// HubSKU identifies a SKU at a particular hub.
+type HubSKU struct {
+	HubID string `json:"hub_id"`
+	SKUID string `json:"sku_id"`
+}
+
+// Validator validates hubs and SKUs against IMS. It deduplicates IDs, uses
+// the bulk validation endpoint when enabled and keeps an in-process TTL cache
+// of both valid and invalid results. Results carrying stock levels go stale
+// quickly and are kept only for the short quantity TTL. Transient errors are
+// never cached.
+type Validator struct {
+	client      IMSClient
+	cache       *ttlCache
+	positiveTTL time.Duration
+	negativeTTL time.Duration
+	quantityTTL time.Duration
+	bulkEnabled bool
+}
+
+// NewValidator builds a Validator over client from the ims_validation config
+// section.
+func NewValidator(ctx context.Context, client IMSClient) *Validator {
+	positiveTTL := config.GetDuration(ctx, "ims_validation.cache_ttl")
+	if positiveTTL <= 0 {
+		positiveTTL = defaultPositiveTTL
+	}
+	negativeTTL := config.GetDuration(ctx, "ims_validation.negative_cache_ttl")
+	if negativeTTL <= 0 {
+		negativeTTL = defaultNegativeTTL
+	}
+	quantityTTL := config.GetDuration(ctx, "ims_validation.quantity_cache_ttl")
+	if quantityTTL <= 0 {
+		quantityTTL = defaultQuantityTTL
+	}
+
+	return &Validator{
+		client:      client,
+		cache:       newTTLCache(),
+		positiveTTL: positiveTTL,
+		negativeTTL: negativeTTL,
+		quantityTTL: quantityTTL,
+		bulkEnabled: config.GetBool(ctx, "ims_validation.bulk_enabled"),
+	}
+}
+
+// Prefetch validates every distinct hub and (hub, SKU) pair that is not
+// already cached, so the per-row checks that follow are served from the cache.
+func (v *Validator) Prefetch(ctx context.Context, hubIDs []string, pairs []HubSKU) {
+	hubs := v.uncachedHubs(hubIDs)
+	skus := v.uncachedPairs(pairs)
+	if len(hubs) == 0 && len(skus) == 0 {
+		return
+	}
+
+	if v.bulkEnabled {
+		v.prefetchBulk(ctx, hubs, skus)
+		hubs = v.uncachedHubs(hubs)
+		skus = v.uncachedPairs(skus)
+	}
+
+	for _, id := range hubs {
+		v.ValidateHub(ctx, id)
+	}
+	for _, pair := range skus {
+		v.ValidateSKUOnHub(ctx, pair.HubID, pair.SKUID)
+	}
+}
+
+// ValidateHub classifies hubID.
+func (v *Validator) ValidateHub(ctx context.Context, hubID string) ValidationResult {
+	if result, ok := v.cache.Get(hubKey(hubID)); ok {
+		return result
+	}
+
+	result := v.client.ValidateHub(ctx, hubID)
+	v.remember(hubKey(hubID), result)
+	return result
+}
+
+// ValidateSKUOnHub classifies skuID at hubID and, when IMS provides it,
+// reports how many units are available there.
+func (v *Validator) ValidateSKUOnHub(ctx context.Context, hubID string, skuID string) ValidationResult {
+	key := pairKey(HubSKU{HubID: hubID, SKUID: skuID})
+	if result, ok := v.cache.Get(key); ok {
+		return result
+	}
+
+	result := v.client.ValidateSKUOnHub(ctx, hubID, skuID)
+	v.remember(key, result)
+	return result
+}
+
+func (v *Validator) prefetchBulk(ctx context.Context, hubIDs []string, pairs []HubSKU) {
+	for len(hubIDs) > 0 || len(pairs) > 0 {
+		var hubChunk []string
+		var pairChunk []HubSKU
+		hubChunk, hubIDs = splitChunk(hubIDs, bulkChunkSize)
+		pairChunk, pairs = splitChunk(pairs, bulkChunkSize-len(hubChunk))
+
+		result, err := v.client.ValidateBulk(ctx, hubChunk, pairChunk)
+		if err != nil {
+			log.Warnf(i18n.Translate(ctx, "IMS bulk validation failed, falling back to per-ID checks: %v"), err)
+			return
+		}
+
+		for id, r := range result.Hubs {
+			v.remember(hubKey(id), r)
+		}
+		for pair, r := range result.HubSKUs {
+			v.remember(pairKey(pair), r)
+		}
+	}
+}
+
+func (v *Validator) remember(key string, result ValidationResult) {
+	switch result.Status {
+	case ResultValid:
+		ttl := v.positiveTTL
+		if result.AvailableQty != nil && v.quantityTTL < ttl {
+			ttl = v.quantityTTL
+		}
+		v.cache.Set(key, result, ttl)
+	case ResultInvalid, ResultNotFound:
+		v.cache.Set(key, result, v.negativeTTL)
+	}
+}
+
+// uncachedHubs returns the distinct, non-empty hub IDs with no cached result.
+func (v *Validator) uncachedHubs(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	var out []string
+	for _, id := range ids {
+		if _, dup := seen[id]; dup || id == "" {
+			continue
+		}
+		seen[id] = struct{}{}
+		if _, ok := v.cache.Get(hubKey(id)); !ok {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// uncachedPairs returns the distinct, complete (hub, SKU) pairs with no cached result.
+func (v *Validator) uncachedPairs(pairs []HubSKU) []HubSKU {
+	seen := make(map[HubSKU]struct{}, len(pairs))
+	var out []HubSKU
+	for _, pair := range pairs {
+		if _, dup := seen[pair]; dup || pair.HubID == "" || pair.SKUID == "" {
+			continue
+		}
+		seen[pair] = struct{}{}
+		if _, ok := v.cache.Get(pairKey(pair)); !ok {
+			out = append(out, pair)
+		}
+	}
+	return out
+}
+
+func splitChunk[T any](items []T, size int) ([]T, []T) {
+	if size <= 0 {
+		return nil, items
+	}
+	if len(items) <= size {
+		return items, nil
+	}
+	return items[:size], items[size:]
+}
+
+func hubKey(id string) string    { return "hub:" + id }
+func pairKey(pair HubSKU) string { return "sku:" + pair.HubID + ":" + pair.SKUID }
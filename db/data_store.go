@@ -9,3 +9,43 @@ func WebhookCollection() *mongo.Collection {
 func OrderCollection() *mongo.Collection {
 	return Client.Database("oms").Collection("orders")
 }
+
+func IdempotencyCollection() *mongo.Collection {
+	return Client.Database("oms").Collection("idempotency_keys")
+}
+
+func BulkJobCollection() *mongo.Collection {
+	return Client.Database("oms").Collection("bulk_jobs")
+}
+
+func EmittedEventCollection() *mongo.Collection {
+	return Client.Database("oms").Collection("emitted_events")
+}
+
+func BulkJobRowCollection() *mongo.Collection {
+	return Client.Database("oms").Collection("bulk_job_rows")
+}
+
+func OrderAmendmentCollection() *mongo.Collection {
+	return Client.Database("oms").Collection("order_amendments")
+}
+
+func ReturnCollection() *mongo.Collection {
+	return Client.Database("oms").Collection("returns")
+}
+
+func ShipmentCollection() *mongo.Collection {
+	return Client.Database("oms").Collection("shipments")
+}
+
+func TenantSettingsCollection() *mongo.Collection {
+	return Client.Database("oms").Collection("tenant_settings")
+}
+
+func HoldRetryAttemptCollection() *mongo.Collection {
+	return Client.Database("oms").Collection("hold_retry_attempts")
+}
+
+func CustomerCollection() *mongo.Collection {
+	return Client.Database("oms").Collection("customers")
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IdempotencyKeyTTL is how long an Idempotency-Key is remembered.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// EnsureIndexes creates the indexes the services rely on. It is safe to call
+// on every start-up.
+func EnsureIndexes(ctx context.Context) error {
+	// Order IDs are unique across tenants, so lookups by order_id alone
+	// cannot reach another tenant's order.
+	_, err := OrderCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "order_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = IdempotencyCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(IdempotencyKeyTTL.Seconds())),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = BulkJobCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "job_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "checksum", Value: 1}, {Key: "created_at", Value: -1}}},
+		{
+			Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "active_checksum", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"active_checksum": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = BulkJobRowCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "job_id", Value: 1}, {Key: "row_number", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = EmittedEventCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "tenant_id", Value: 1},
+			{Key: "order_id", Value: 1},
+			{Key: "job_id", Value: 1},
+			{Key: "event", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Used by the reservation expiry sweeper.
+	_, err = OrderCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "reservation.status", Value: 1}, {Key: "reservation.expires_at", Value: 1}},
+		Options: options.Index().SetPartialFilterExpression(bson.M{"reservation.status": "reserved"}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Used to roll up the status of split orders from their children.
+	_, err = OrderCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "parent_order_id", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Used for date range reporting and SLA queries on orders.
+	_, err = OrderCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "customer_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "updated_at", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "customer_id", Value: 1}, {Key: "order_date", Value: -1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"order_date": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "promise_date", Value: 1}, {Key: "status", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"promise_date": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Used to find held orders per hub and SKU, oldest first.
+	_, err = OrderCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "hub_id", Value: 1}, {Key: "sku_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = ShipmentCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "shipment_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "order_id", Value: 1}, {Key: "shipped_at", Value: 1}}},
+		// Used to retry failed inventory commits.
+		{
+			Keys:    bson.D{{Key: "inventory_committed", Value: 1}, {Key: "shipped_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"inventory_committed": false}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = ReturnCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "rma_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "order_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		// Used to retry failed restocks.
+		{
+			Keys:    bson.D{{Key: "restock_pending", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"restock_pending": true}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// One amendment per order and version; concurrent amendments of the same
+	// order collide here.
+	_, err = OrderAmendmentCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "order_id", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = TenantSettingsCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = HoldRetryAttemptCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "order_id", Value: 1}, {Key: "attempted_at", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = CustomerCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// One customer per tenant and external ID; concurrent imports of a
+		// new customer collide here.
+		{
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "external_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Used to look up a customer within its tenant.
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "customer_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Used to list a customer's orders within its tenant, newest first.
+	_, err = OrderCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "customer.id", Value: 1}, {Key: "created_at", Value: -1}},
+		Options: options.Index().SetPartialFilterExpression(bson.M{"customer.id": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof(i18n.Translate(ctx, "MongoDB indexes ensured"))
+	return nil
+}
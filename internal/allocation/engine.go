@@ -0,0 +1,174 @@
+// Package allocation chooses the fulfilling hub for orders submitted without
+// a hub_id.
+package allocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/IMS_APIS"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+const defaultHubListTTL = 5 * time.Minute
+
+// ErrNoHubAvailable means the tenant has no hub that could be chosen.
+var ErrNoHubAvailable = errors.New("no hub available for order")
+
+// Request is an order that needs a hub.
+type Request struct {
+	TenantID            int
+	Lines               []models.OrderLineInput
+	ShippingCoordinates *models.Coordinates
+	// HubPriority is the tenant's preferred hub order.
+	HubPriority []string
+}
+
+// Strategy narrows down the candidate hubs for an order. Select returns the
+// candidates it prefers, best first, and the reason for its choice. Returning
+// no candidates means the strategy has no preference and leaves the decision
+// to the next one. Errors abort the allocation and should only be returned
+// for outages.
+type Strategy interface {
+	Name() models.AllocationStrategy
+	Select(ctx context.Context, req Request, candidates []IMS_APIS.Hub) ([]IMS_APIS.Hub, string, error)
+}
+
+// Engine applies strategies in turn until a single hub remains.
+type Engine struct {
+	ims        IMS_APIS.IMSClient
+	validator  *IMS_APIS.Validator
+	strategies map[models.AllocationStrategy]Strategy
+	defaults   []models.AllocationStrategy
+
+	hubTTL time.Duration
+	mu     sync.Mutex
+	hubs   map[int]hubList
+}
+
+type hubList struct {
+	hubs      []IMS_APIS.Hub
+	expiresAt time.Time
+}
+
+// NewEngine builds an Engine listing hubs through ims, checking stock through
+// validator and reading hub load from load. The default strategy order comes
+// from hub_allocation.strategies.
+func NewEngine(ctx context.Context, ims IMS_APIS.IMSClient, validator *IMS_APIS.Validator, load LoadCounter) *Engine {
+	e := &Engine{
+		ims:        ims,
+		validator:  validator,
+		strategies: make(map[models.AllocationStrategy]Strategy),
+		defaults:   models.DefaultAllocationStrategies,
+		hubTTL:     config.GetDuration(ctx, "hub_allocation.hub_list_ttl"),
+		hubs:       make(map[int]hubList),
+	}
+	if e.hubTTL <= 0 {
+		e.hubTTL = defaultHubListTTL
+	}
+
+	for _, s := range []Strategy{
+		&FullStock{Validator: validator},
+		&TenantPriority{},
+		&Nearest{},
+		&LeastLoaded{Load: load},
+	} {
+		e.Register(s)
+	}
+
+	if names := config.GetStringSlice(ctx, "hub_allocation.strategies"); len(names) > 0 {
+		e.defaults = nil
+		for _, name := range names {
+			s := models.AllocationStrategy(name)
+			if !s.IsValid() {
+				log.Warnf(i18n.Translate(ctx, "ignoring unknown hub allocation strategy %s"), name)
+				continue
+			}
+			e.defaults = append(e.defaults, s)
+		}
+	}
+	return e
+}
+
+// Register adds or replaces a strategy.
+func (e *Engine) Register(s Strategy) {
+	e.strategies[s.Name()] = s
+}
+
+// Allocate chooses a hub for req, applying order or, when it is empty, the
+// configured default strategy order.
+func (e *Engine) Allocate(ctx context.Context, req Request, order []models.AllocationStrategy) (*models.HubAllocation, error) {
+	if len(order) == 0 {
+		order = e.defaults
+	}
+
+	candidates, err := e.tenantHubs(ctx, req.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoHubAvailable
+	}
+
+	allocation := &models.HubAllocation{Strategy: models.AllocationFirstAvailable, Candidates: len(candidates)}
+	var reasons []string
+	for _, name := range order {
+		if len(candidates) == 1 {
+			break
+		}
+		s, ok := e.strategies[name]
+		if !ok {
+			continue
+		}
+
+		chosen, reason, err := s.Select(ctx, req, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(chosen) == 0 {
+			continue
+		}
+		candidates = chosen
+		allocation.Strategy = name
+		reasons = append(reasons, reason)
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, "no strategy had a preference, first hub listed by IMS")
+	}
+	allocation.HubID = candidates[0].ID
+	allocation.Reason = strings.Join(reasons, "; ")
+	allocation.AllocatedAt = time.Now().UTC()
+	return allocation, nil
+}
+
+// tenantHubs lists a tenant's hubs, caching the answer for hubTTL. IMS not
+// knowing the tenant is reported as ErrNoHubAvailable.
+func (e *Engine) tenantHubs(ctx context.Context, tenantID int) ([]IMS_APIS.Hub, error) {
+	e.mu.Lock()
+	cached, ok := e.hubs[tenantID]
+	e.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.hubs, nil
+	}
+
+	hubs, err := e.ims.ListHubs(ctx, tenantID)
+	if err != nil {
+		if IMS_APIS.IsTransient(err) || IMS_APIS.IsAuthError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrNoHubAvailable, err)
+	}
+
+	e.mu.Lock()
+	e.hubs[tenantID] = hubList{hubs: hubs, expiresAt: time.Now().Add(e.hubTTL)}
+	e.mu.Unlock()
+	return hubs, nil
+}
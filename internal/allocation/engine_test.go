@@ -0,0 +1,144 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RohitGupta-omniful/OMS/IMS_APIS"
+	"github.com/RohitGupta-omniful/OMS/models"
+)
+
+func newTestEngine(ims *fakeIMS, load *fakeLoad) *Engine {
+	ctx := context.Background()
+	return NewEngine(ctx, ims, IMS_APIS.NewValidator(ctx, ims), load)
+}
+
+func TestEngineAllocate(t *testing.T) {
+	ims := &fakeIMS{
+		hubs: map[int][]IMS_APIS.Hub{
+			1: {hubAt("h1", 19.0, 72.8), hubAt("h2", 28.7, 77.1), hubAt("h3", 28.7, 77.1)},
+		},
+		skus: map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult{
+			{HubID: "h1", SKUID: "sku-a"}: stocked(5),
+			{HubID: "h2", SKUID: "sku-a"}: stocked(5),
+			{HubID: "h3", SKUID: "sku-a"}: stocked(5),
+		},
+	}
+	load := &fakeLoad{counts: map[string]int{"h2": 3, "h3": 1}}
+	req := Request{
+		TenantID:            1,
+		Lines:               []models.OrderLineInput{{SKUID: "sku-a", Qty: 1}},
+		ShippingCoordinates: &models.Coordinates{Latitude: 28.6, Longitude: 77.2},
+	}
+
+	tests := []struct {
+		name         string
+		req          func(Request) Request
+		order        []models.AllocationStrategy
+		wantHub      string
+		wantStrategy models.AllocationStrategy
+	}{
+		{
+			name:         "default order narrows until one hub remains",
+			wantHub:      "h3",
+			wantStrategy: models.AllocationLeastLoaded,
+		},
+		{
+			name:         "tenant priority decides before nearest",
+			req:          func(r Request) Request { r.HubPriority = []string{"h1"}; return r },
+			wantHub:      "h1",
+			wantStrategy: models.AllocationTenantPriority,
+		},
+		{
+			name:         "custom order",
+			order:        []models.AllocationStrategy{models.AllocationLeastLoaded, models.AllocationNearest},
+			wantHub:      "h1",
+			wantStrategy: models.AllocationLeastLoaded,
+		},
+		{
+			name:         "unknown strategies are skipped",
+			order:        []models.AllocationStrategy{"coin_flip", models.AllocationNearest},
+			wantHub:      "h2",
+			wantStrategy: models.AllocationNearest,
+		},
+		{
+			name:         "no preference falls back to the first hub",
+			req:          func(r Request) Request { r.ShippingCoordinates = nil; return r },
+			order:        []models.AllocationStrategy{models.AllocationNearest, models.AllocationTenantPriority},
+			wantHub:      "h1",
+			wantStrategy: models.AllocationFirstAvailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := req
+			if tt.req != nil {
+				r = tt.req(r)
+			}
+			got, err := newTestEngine(ims, load).Allocate(context.Background(), r, tt.order)
+			if err != nil {
+				t.Fatalf("Allocate: %v", err)
+			}
+			if got.HubID != tt.wantHub || got.Strategy != tt.wantStrategy {
+				t.Errorf("allocated %s by %s, want %s by %s", got.HubID, got.Strategy, tt.wantHub, tt.wantStrategy)
+			}
+			if got.Candidates != 3 || got.Reason == "" || got.AllocatedAt.IsZero() {
+				t.Errorf("allocation = %+v, want 3 candidates, a reason and a time", got)
+			}
+		})
+	}
+}
+
+func TestEngineAllocateErrors(t *testing.T) {
+	req := Request{TenantID: 1, Lines: []models.OrderLineInput{{SKUID: "sku-a", Qty: 1}}}
+
+	tests := []struct {
+		name      string
+		ims       *fakeIMS
+		load      *fakeLoad
+		order     []models.AllocationStrategy
+		wantNoHub bool
+	}{
+		{name: "tenant has no hubs", ims: &fakeIMS{}, wantNoHub: true},
+		{
+			name:      "IMS does not know the tenant",
+			ims:       &fakeIMS{listErr: &IMS_APIS.RequestError{StatusCode: 404, Message: "unknown tenant"}},
+			wantNoHub: true,
+		},
+		{name: "IMS unavailable", ims: &fakeIMS{listErr: errors.New("connection refused")}},
+		{
+			name:  "strategy outage",
+			ims:   &fakeIMS{hubs: map[int][]IMS_APIS.Hub{1: {hub("h1"), hub("h2")}}},
+			load:  &fakeLoad{err: errors.New("mongo down")},
+			order: []models.AllocationStrategy{models.AllocationLeastLoaded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestEngine(tt.ims, tt.load).Allocate(context.Background(), req, tt.order)
+			if err == nil {
+				t.Fatal("Allocate: expected an error")
+			}
+			if got := errors.Is(err, ErrNoHubAvailable); got != tt.wantNoHub {
+				t.Errorf("err = %v, ErrNoHubAvailable = %v, want %v", err, got, tt.wantNoHub)
+			}
+		})
+	}
+}
+
+func TestEngineCachesHubList(t *testing.T) {
+	ims := &fakeIMS{hubs: map[int][]IMS_APIS.Hub{1: {hub("h1")}}}
+	e := newTestEngine(ims, &fakeLoad{})
+
+	for i := 0; i < 3; i++ {
+		if _, err := e.Allocate(context.Background(), Request{TenantID: 1}, nil); err != nil {
+			t.Fatalf("Allocate: %v", err)
+		}
+	}
+	if ims.listCalls != 1 {
+		t.Errorf("ListHubs called %d times, want 1", ims.listCalls)
+	}
+}
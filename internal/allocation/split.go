@@ -0,0 +1,119 @@
+package allocation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SplitPlan assigns an order's lines to the hubs fulfilling them.
+type SplitPlan struct {
+	// Candidates is the number of hubs considered.
+	Candidates int
+	Groups     []SplitGroup
+}
+
+// SplitGroup is the part of an order fulfilled by one hub. Lines holds
+// indexes into Request.Lines.
+type SplitGroup struct {
+	HubID  string
+	Lines  []int
+	Reason string
+}
+
+// Split plans how to fulfil an order from several hubs when no single hub
+// stocks every line. Hubs covering the most remaining lines are chosen
+// first, ties going to the tenant's hub priority. No plan is returned when
+// one hub can fulfil the whole order or some line cannot be fulfilled by
+// any hub.
+func (e *Engine) Split(ctx context.Context, req Request) (*SplitPlan, error) {
+	candidates, err := e.tenantHubs(ctx, req.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoHubAvailable
+	}
+
+	coverage, err := lineCoverage(ctx, e.validator, req.Lines, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	rank := priorityRank(req.HubPriority)
+	sort.SliceStable(order, func(a, b int) bool {
+		return rank(candidates[order[a]].ID) < rank(candidates[order[b]].ID)
+	})
+
+	for _, i := range order {
+		if countTrue(coverage[i]) == len(req.Lines) {
+			return nil, nil
+		}
+	}
+
+	remaining := make(map[int]bool, len(req.Lines))
+	for j := range req.Lines {
+		remaining[j] = true
+	}
+
+	plan := &SplitPlan{Candidates: len(candidates)}
+	for len(remaining) > 0 {
+		best, bestCount := -1, 0
+		for _, i := range order {
+			n := 0
+			for j := range remaining {
+				if coverage[i][j] {
+					n++
+				}
+			}
+			if n > bestCount {
+				best, bestCount = i, n
+			}
+		}
+		if best < 0 {
+			// Some line is not stocked in full anywhere.
+			return nil, nil
+		}
+
+		group := SplitGroup{HubID: candidates[best].ID}
+		for j := range req.Lines {
+			if remaining[j] && coverage[best][j] {
+				group.Lines = append(group.Lines, j)
+				delete(remaining, j)
+			}
+		}
+		group.Reason = fmt.Sprintf("hub %s stocks lines %s", group.HubID, lineList(group.Lines))
+		plan.Groups = append(plan.Groups, group)
+	}
+	return plan, nil
+}
+
+// priorityRank returns a function ranking hubs by their position in the
+// priority list. Hubs not in the list rank last.
+func priorityRank(priority []string) func(string) int {
+	ranks := make(map[string]int, len(priority))
+	for i, id := range priority {
+		if _, ok := ranks[id]; !ok {
+			ranks[id] = i
+		}
+	}
+	return func(id string) int {
+		if r, ok := ranks[id]; ok {
+			return r
+		}
+		return len(priority)
+	}
+}
+
+func lineList(indexes []int) string {
+	parts := make([]string, len(indexes))
+	for i, j := range indexes {
+		parts[i] = fmt.Sprint(j + 1)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,146 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/RohitGupta-omniful/OMS/IMS_APIS"
+	"github.com/RohitGupta-omniful/OMS/models"
+)
+
+func TestEngineSplit(t *testing.T) {
+	lines := []models.OrderLineInput{
+		{SKUID: "sku-a", Qty: 1},
+		{SKUID: "sku-b", Qty: 1},
+		{SKUID: "sku-c", Qty: 1},
+	}
+	hubs := []IMS_APIS.Hub{hub("h1"), hub("h2"), hub("h3")}
+
+	tests := []struct {
+		name     string
+		skus     map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult
+		priority []string
+		want     []SplitGroup
+		wantNil  bool
+	}{
+		{
+			name: "hub covering most lines goes first",
+			skus: map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult{
+				{HubID: "h1", SKUID: "sku-a"}: stocked(1),
+				{HubID: "h2", SKUID: "sku-a"}: stocked(1),
+				{HubID: "h2", SKUID: "sku-b"}: stocked(1),
+				{HubID: "h3", SKUID: "sku-c"}: stocked(1),
+			},
+			want: []SplitGroup{
+				{HubID: "h2", Lines: []int{0, 1}, Reason: "hub h2 stocks lines 1, 2"},
+				{HubID: "h3", Lines: []int{2}, Reason: "hub h3 stocks lines 3"},
+			},
+		},
+		{
+			name: "ties go to the tenant's priority",
+			skus: map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult{
+				{HubID: "h1", SKUID: "sku-a"}: stocked(1),
+				{HubID: "h1", SKUID: "sku-b"}: stocked(1),
+				{HubID: "h2", SKUID: "sku-a"}: stocked(1),
+				{HubID: "h2", SKUID: "sku-b"}: stocked(1),
+				{HubID: "h3", SKUID: "sku-c"}: stocked(1),
+			},
+			priority: []string{"h3", "h2"},
+			want: []SplitGroup{
+				{HubID: "h2", Lines: []int{0, 1}, Reason: "hub h2 stocks lines 1, 2"},
+				{HubID: "h3", Lines: []int{2}, Reason: "hub h3 stocks lines 3"},
+			},
+		},
+		{
+			name: "ties without priority keep IMS order",
+			skus: map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult{
+				{HubID: "h1", SKUID: "sku-a"}: stocked(1),
+				{HubID: "h2", SKUID: "sku-b"}: stocked(1),
+				{HubID: "h3", SKUID: "sku-c"}: stocked(1),
+			},
+			want: []SplitGroup{
+				{HubID: "h1", Lines: []int{0}, Reason: "hub h1 stocks lines 1"},
+				{HubID: "h2", Lines: []int{1}, Reason: "hub h2 stocks lines 2"},
+				{HubID: "h3", Lines: []int{2}, Reason: "hub h3 stocks lines 3"},
+			},
+		},
+		{
+			name: "one hub stocks everything",
+			skus: map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult{
+				{HubID: "h1", SKUID: "sku-a"}: stocked(1),
+				{HubID: "h3", SKUID: "sku-a"}: stocked(1),
+				{HubID: "h3", SKUID: "sku-b"}: stocked(1),
+				{HubID: "h3", SKUID: "sku-c"}: stocked(1),
+			},
+			wantNil: true,
+		},
+		{
+			name: "a line no hub can fulfil",
+			skus: map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult{
+				{HubID: "h1", SKUID: "sku-a"}: stocked(1),
+				{HubID: "h2", SKUID: "sku-b"}: stocked(1),
+				{HubID: "h3", SKUID: "sku-c"}: stocked(0),
+			},
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ims := &fakeIMS{hubs: map[int][]IMS_APIS.Hub{1: hubs}, skus: tt.skus}
+			plan, err := newTestEngine(ims, &fakeLoad{}).Split(context.Background(), Request{
+				TenantID:    1,
+				Lines:       lines,
+				HubPriority: tt.priority,
+			})
+			if err != nil {
+				t.Fatalf("Split: %v", err)
+			}
+			if tt.wantNil {
+				if plan != nil {
+					t.Errorf("plan = %+v, want none", plan)
+				}
+				return
+			}
+			if plan == nil {
+				t.Fatal("Split: no plan")
+			}
+			if plan.Candidates != len(hubs) {
+				t.Errorf("Candidates = %d, want %d", plan.Candidates, len(hubs))
+			}
+			if !reflect.DeepEqual(plan.Groups, tt.want) {
+				t.Errorf("groups = %+v, want %+v", plan.Groups, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineSplitErrors(t *testing.T) {
+	req := Request{TenantID: 1, Lines: []models.OrderLineInput{{SKUID: "sku-a", Qty: 1}}}
+
+	_, err := newTestEngine(&fakeIMS{}, &fakeLoad{}).Split(context.Background(), req)
+	if !errors.Is(err, ErrNoHubAvailable) {
+		t.Errorf("no hubs: err = %v, want %v", err, ErrNoHubAvailable)
+	}
+
+	ims := &fakeIMS{
+		hubs: map[int][]IMS_APIS.Hub{1: {hub("h1")}},
+		skus: map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult{
+			{HubID: "h1", SKUID: "sku-a"}: {Status: IMS_APIS.ResultTransientError, Err: errors.New("timeout")},
+		},
+	}
+	if _, err := newTestEngine(ims, &fakeLoad{}).Split(context.Background(), req); err == nil {
+		t.Error("transient IMS error: expected an error")
+	}
+}
+
+func TestPriorityRank(t *testing.T) {
+	rank := priorityRank([]string{"h2", "h1", "h2"})
+	for id, want := range map[string]int{"h2": 0, "h1": 1, "h9": 3} {
+		if got := rank(id); got != want {
+			t.Errorf("rank(%q) = %d, want %d", id, got, want)
+		}
+	}
+}
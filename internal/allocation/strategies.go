@@ -0,0 +1,171 @@
+package allocation
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/RohitGupta-omniful/OMS/IMS_APIS"
+	"github.com/RohitGupta-omniful/OMS/models"
+)
+
+const earthRadiusKm = 6371.0
+
+// LoadCounter reports how many open orders each hub has.
+type LoadCounter interface {
+	OpenOrderCounts(ctx context.Context, hubIDs []string) (map[string]int, error)
+}
+
+// FullStock keeps the hubs that stock every line of the order in full.
+type FullStock struct {
+	Validator *IMS_APIS.Validator
+}
+
+func (s *FullStock) Name() models.AllocationStrategy { return models.AllocationFullStock }
+
+func (s *FullStock) Select(ctx context.Context, req Request, candidates []IMS_APIS.Hub) ([]IMS_APIS.Hub, string, error) {
+	coverage, err := lineCoverage(ctx, s.Validator, req.Lines, candidates)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var stocked []IMS_APIS.Hub
+	for i, hub := range candidates {
+		if countTrue(coverage[i]) == len(req.Lines) {
+			stocked = append(stocked, hub)
+		}
+	}
+
+	if len(stocked) == 0 {
+		return nil, "", nil
+	}
+	return stocked, fmt.Sprintf("%d of %d hubs stock every line", len(stocked), len(candidates)), nil
+}
+
+// lineCoverage reports, for each candidate hub, which lines it can fulfil in
+// full.
+func lineCoverage(ctx context.Context, validator *IMS_APIS.Validator, lines []models.OrderLineInput, candidates []IMS_APIS.Hub) ([][]bool, error) {
+	hubIDs := make([]string, 0, len(candidates))
+	pairs := make([]IMS_APIS.HubSKU, 0, len(candidates)*len(lines))
+	for _, hub := range candidates {
+		hubIDs = append(hubIDs, hub.ID)
+		for _, line := range lines {
+			pairs = append(pairs, IMS_APIS.HubSKU{HubID: hub.ID, SKUID: line.SKUID})
+		}
+	}
+	validator.Prefetch(ctx, hubIDs, pairs)
+
+	coverage := make([][]bool, len(candidates))
+	for i, hub := range candidates {
+		coverage[i] = make([]bool, len(lines))
+		for j, line := range lines {
+			result := validator.ValidateSKUOnHub(ctx, hub.ID, line.SKUID)
+			if result.IsTransient() || result.IsAuthError() {
+				return nil, result.Err
+			}
+			coverage[i][j] = result.CanFulfil(line.Qty)
+		}
+	}
+	return coverage, nil
+}
+
+func countTrue(values []bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// TenantPriority picks the first candidate in the tenant's hub priority list.
+type TenantPriority struct{}
+
+func (s *TenantPriority) Name() models.AllocationStrategy { return models.AllocationTenantPriority }
+
+func (s *TenantPriority) Select(ctx context.Context, req Request, candidates []IMS_APIS.Hub) ([]IMS_APIS.Hub, string, error) {
+	for rank, id := range req.HubPriority {
+		for _, hub := range candidates {
+			if hub.ID == id {
+				return []IMS_APIS.Hub{hub}, fmt.Sprintf("hub %s is priority %d for the tenant", id, rank+1), nil
+			}
+		}
+	}
+	return nil, "", nil
+}
+
+// Nearest picks the candidate closest to the shipping coordinates. Hubs
+// without a location are ignored.
+type Nearest struct{}
+
+func (s *Nearest) Name() models.AllocationStrategy { return models.AllocationNearest }
+
+func (s *Nearest) Select(ctx context.Context, req Request, candidates []IMS_APIS.Hub) ([]IMS_APIS.Hub, string, error) {
+	if req.ShippingCoordinates == nil {
+		return nil, "", nil
+	}
+
+	var nearest []IMS_APIS.Hub
+	best := math.MaxFloat64
+	for _, hub := range candidates {
+		if hub.Latitude == nil || hub.Longitude == nil {
+			continue
+		}
+		d := distanceKm(*req.ShippingCoordinates, models.Coordinates{Latitude: *hub.Latitude, Longitude: *hub.Longitude})
+		switch {
+		case d < best:
+			best = d
+			nearest = []IMS_APIS.Hub{hub}
+		case d == best:
+			nearest = append(nearest, hub)
+		}
+	}
+
+	if len(nearest) == 0 {
+		return nil, "", nil
+	}
+	return nearest, fmt.Sprintf("hub %s is %.1f km from the shipping address", nearest[0].ID, best), nil
+}
+
+// LeastLoaded picks the candidates with the fewest open orders.
+type LeastLoaded struct {
+	Load LoadCounter
+}
+
+func (s *LeastLoaded) Name() models.AllocationStrategy { return models.AllocationLeastLoaded }
+
+func (s *LeastLoaded) Select(ctx context.Context, req Request, candidates []IMS_APIS.Hub) ([]IMS_APIS.Hub, string, error) {
+	hubIDs := make([]string, 0, len(candidates))
+	for _, hub := range candidates {
+		hubIDs = append(hubIDs, hub.ID)
+	}
+
+	counts, err := s.Load.OpenOrderCounts(ctx, hubIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var least []IMS_APIS.Hub
+	best := math.MaxInt
+	for _, hub := range candidates {
+		switch n := counts[hub.ID]; {
+		case n < best:
+			best = n
+			least = []IMS_APIS.Hub{hub}
+		case n == best:
+			least = append(least, hub)
+		}
+	}
+	return least, fmt.Sprintf("hub %s has %d open orders", least[0].ID, best), nil
+}
+
+// distanceKm is the great-circle distance between a and b.
+func distanceKm(a, b models.Coordinates) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,245 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/RohitGupta-omniful/OMS/IMS_APIS"
+	"github.com/RohitGupta-omniful/OMS/models"
+)
+
+// fakeIMS answers from fixed hub lists and per-(hub, SKU) results. Pairs
+// without a result are reported as not found.
+type fakeIMS struct {
+	hubs      map[int][]IMS_APIS.Hub
+	listErr   error
+	skus      map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult
+	listCalls int
+}
+
+func (f *fakeIMS) ValidateHub(ctx context.Context, hubID string) IMS_APIS.ValidationResult {
+	return IMS_APIS.ValidationResult{Status: IMS_APIS.ResultValid}
+}
+
+func (f *fakeIMS) ValidateSKUOnHub(ctx context.Context, hubID string, skuID string) IMS_APIS.ValidationResult {
+	if r, ok := f.skus[IMS_APIS.HubSKU{HubID: hubID, SKUID: skuID}]; ok {
+		return r
+	}
+	return IMS_APIS.ValidationResult{Status: IMS_APIS.ResultNotFound}
+}
+
+func (f *fakeIMS) ValidateBulk(ctx context.Context, hubIDs []string, pairs []IMS_APIS.HubSKU) (IMS_APIS.BulkValidationResult, error) {
+	return IMS_APIS.BulkValidationResult{}, errors.New("bulk validation not supported")
+}
+
+func (f *fakeIMS) ListHubs(ctx context.Context, tenantID int) ([]IMS_APIS.Hub, error) {
+	f.listCalls++
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	return f.hubs[tenantID], nil
+}
+
+// fakeLoad reports fixed open order counts; hubs without a count have none.
+type fakeLoad struct {
+	counts map[string]int
+	err    error
+}
+
+func (f *fakeLoad) OpenOrderCounts(ctx context.Context, hubIDs []string) (map[string]int, error) {
+	return f.counts, f.err
+}
+
+func hub(id string) IMS_APIS.Hub { return IMS_APIS.Hub{ID: id} }
+
+func hubAt(id string, lat, lng float64) IMS_APIS.Hub {
+	return IMS_APIS.Hub{ID: id, Latitude: &lat, Longitude: &lng}
+}
+
+func stocked(qty int) IMS_APIS.ValidationResult {
+	return IMS_APIS.ValidationResult{Status: IMS_APIS.ResultValid, AvailableQty: &qty}
+}
+
+func hubIDs(hubs []IMS_APIS.Hub) []string {
+	if len(hubs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(hubs))
+	for i, h := range hubs {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+func TestFullStockSelect(t *testing.T) {
+	candidates := []IMS_APIS.Hub{hub("h1"), hub("h2"), hub("h3")}
+	lines := []models.OrderLineInput{{SKUID: "sku-a", Qty: 2}, {SKUID: "sku-b", Qty: 1}}
+
+	tests := []struct {
+		name    string
+		skus    map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "keeps hubs stocking every line",
+			skus: map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult{
+				{HubID: "h1", SKUID: "sku-a"}: stocked(5),
+				{HubID: "h1", SKUID: "sku-b"}: stocked(1),
+				{HubID: "h2", SKUID: "sku-a"}: stocked(1),
+				{HubID: "h2", SKUID: "sku-b"}: stocked(9),
+				{HubID: "h3", SKUID: "sku-a"}: {Status: IMS_APIS.ResultValid},
+				{HubID: "h3", SKUID: "sku-b"}: stocked(3),
+			},
+			want: []string{"h1", "h3"},
+		},
+		{
+			name: "no preference when no hub stocks every line",
+			skus: map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult{
+				{HubID: "h1", SKUID: "sku-a"}: stocked(5),
+				{HubID: "h2", SKUID: "sku-b"}: stocked(5),
+			},
+		},
+		{
+			name: "transient IMS error aborts",
+			skus: map[IMS_APIS.HubSKU]IMS_APIS.ValidationResult{
+				{HubID: "h1", SKUID: "sku-a"}: {Status: IMS_APIS.ResultTransientError, Err: errors.New("timeout")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := &FullStock{Validator: IMS_APIS.NewValidator(ctx, &fakeIMS{skus: tt.skus})}
+			got, _, err := s.Select(ctx, Request{Lines: lines}, candidates)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if ids := hubIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("selected %q, want %q", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantPrioritySelect(t *testing.T) {
+	candidates := []IMS_APIS.Hub{hub("h1"), hub("h2"), hub("h3")}
+
+	tests := []struct {
+		name     string
+		priority []string
+		want     []string
+	}{
+		{name: "first listed candidate wins", priority: []string{"h3", "h1"}, want: []string{"h3"}},
+		{name: "hubs that are not candidates are skipped", priority: []string{"h9", "h2"}, want: []string{"h2"}},
+		{name: "no priority list", priority: nil},
+		{name: "no listed hub is a candidate", priority: []string{"h9"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := (&TenantPriority{}).Select(context.Background(), Request{HubPriority: tt.priority}, candidates)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if ids := hubIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("selected %q, want %q", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestNearestSelect(t *testing.T) {
+	dest := &models.Coordinates{Latitude: 28.6, Longitude: 77.2}
+
+	tests := []struct {
+		name       string
+		dest       *models.Coordinates
+		candidates []IMS_APIS.Hub
+		want       []string
+	}{
+		{
+			name:       "closest hub",
+			dest:       dest,
+			candidates: []IMS_APIS.Hub{hubAt("far", 19.0, 72.8), hubAt("near", 28.7, 77.1)},
+			want:       []string{"near"},
+		},
+		{
+			name:       "ties keep candidate order",
+			dest:       dest,
+			candidates: []IMS_APIS.Hub{hubAt("far", 19.0, 72.8), hubAt("a", 28.7, 77.1), hubAt("b", 28.7, 77.1)},
+			want:       []string{"a", "b"},
+		},
+		{
+			name:       "hubs without a location are ignored",
+			dest:       dest,
+			candidates: []IMS_APIS.Hub{hub("unknown"), hubAt("far", 19.0, 72.8)},
+			want:       []string{"far"},
+		},
+		{
+			name:       "no hub has a location",
+			dest:       dest,
+			candidates: []IMS_APIS.Hub{hub("h1"), hub("h2")},
+		},
+		{
+			name:       "no shipping coordinates",
+			candidates: []IMS_APIS.Hub{hubAt("near", 28.7, 77.1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := (&Nearest{}).Select(context.Background(), Request{ShippingCoordinates: tt.dest}, tt.candidates)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if ids := hubIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("selected %q, want %q", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeastLoadedSelect(t *testing.T) {
+	candidates := []IMS_APIS.Hub{hub("h1"), hub("h2"), hub("h3")}
+
+	tests := []struct {
+		name    string
+		load    *fakeLoad
+		want    []string
+		wantErr bool
+	}{
+		{name: "fewest open orders", load: &fakeLoad{counts: map[string]int{"h1": 4, "h2": 1, "h3": 7}}, want: []string{"h2"}},
+		{name: "ties keep candidate order", load: &fakeLoad{counts: map[string]int{"h1": 3, "h2": 5, "h3": 3}}, want: []string{"h1", "h3"}},
+		{name: "hubs without orders count as empty", load: &fakeLoad{counts: map[string]int{"h1": 2}}, want: []string{"h2", "h3"}},
+		{name: "load lookup failure aborts", load: &fakeLoad{err: errors.New("mongo down")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := (&LeastLoaded{Load: tt.load}).Select(context.Background(), Request{}, candidates)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if ids := hubIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("selected %q, want %q", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	delhi := models.Coordinates{Latitude: 28.6139, Longitude: 77.2090}
+	mumbai := models.Coordinates{Latitude: 19.0760, Longitude: 72.8777}
+
+	if d := distanceKm(delhi, delhi); d != 0 {
+		t.Errorf("distance to self = %v, want 0", d)
+	}
+	if d := distanceKm(delhi, mumbai); d < 1140 || d > 1160 {
+		t.Errorf("Delhi to Mumbai = %.1f km, want about 1150", d)
+	}
+}
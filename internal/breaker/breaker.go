@@ -0,0 +1,251 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/omniful/go_commons/config"
+)
+
+// State is the position of a circuit breaker.
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+// ErrOpen is returned without calling the dependency while the breaker is
+// open or its half-open probes are all in flight.
+var ErrOpen = errors.New("circuit breaker is open")
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenDuration     = 30 * time.Second
+	defaultHalfOpenProbes   = 1
+	defaultMaxConcurrent    = 20
+)
+
+// Config tunes a Breaker.
+type Config struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// breaker.
+	FailureThreshold int
+	// OpenDuration is how long the breaker stays open before letting probes
+	// through.
+	OpenDuration time.Duration
+	// HalfOpenProbes is the number of calls let through while half-open; all
+	// of them must succeed to close the breaker again.
+	HalfOpenProbes int
+	// MaxConcurrent bounds the calls in flight at once (the bulkhead).
+	MaxConcurrent int
+}
+
+// ConfigFrom reads a Config from the config section at prefix, e.g.
+// "circuit_breaker.ims", falling back to defaults for unset values.
+func ConfigFrom(ctx context.Context, prefix string) Config {
+	cfg := Config{
+		FailureThreshold: config.GetInt(ctx, prefix+".failure_threshold"),
+		OpenDuration:     config.GetDuration(ctx, prefix+".open_duration"),
+		HalfOpenProbes:   config.GetInt(ctx, prefix+".half_open_probes"),
+		MaxConcurrent:    config.GetInt(ctx, prefix+".max_concurrent"),
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = defaultOpenDuration
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = defaultHalfOpenProbes
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = defaultMaxConcurrent
+	}
+	return cfg
+}
+
+// Status is a point-in-time view of a breaker for health reporting.
+type Status struct {
+	Name                string     `json:"name"`
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	InFlight            int        `json:"in_flight"`
+	MaxConcurrent       int        `json:"max_concurrent"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
+// Breaker is a circuit breaker with a bulkhead limiting concurrent calls.
+// A nil *Breaker lets every call through, so it is optional for callers.
+type Breaker struct {
+	name  string
+	cfg   Config
+	slots chan struct{}
+
+	mu             sync.Mutex
+	state          State
+	failures       int
+	openedAt       time.Time
+	probesInFlight int
+	probeSuccesses int
+}
+
+// New creates a closed breaker.
+func New(name string, cfg Config) *Breaker {
+	return &Breaker{
+		name:  name,
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.MaxConcurrent),
+		state: StateClosed,
+	}
+}
+
+// Name returns the dependency the breaker protects.
+func (b *Breaker) Name() string { return b.name }
+
+// Do runs fn when the breaker allows it, waiting for a bulkhead slot first.
+// isFailure decides which errors count against the breaker; nil counts all of
+// them. ErrOpen is returned without calling fn while the breaker is open.
+func (b *Breaker) Do(ctx context.Context, fn func() error, isFailure func(error) bool) error {
+	if b == nil {
+		return fn()
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-b.slots }()
+
+	probe, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	failed := err != nil && (isFailure == nil || isFailure(err))
+	b.record(probe, failed)
+	return err
+}
+
+// Wait blocks while the breaker is open, returning once probes may be sent
+// or ctx is done.
+func (b *Breaker) Wait(ctx context.Context) error {
+	for {
+		wait := b.remainingOpen()
+		if wait <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// IsOpen reports whether calls are currently being rejected.
+func (b *Breaker) IsOpen() bool {
+	return b.remainingOpen() > 0
+}
+
+// Status returns the breaker's current state.
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advanceLocked(time.Now())
+
+	status := Status{
+		Name:                b.name,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		InFlight:            len(b.slots),
+		MaxConcurrent:       b.cfg.MaxConcurrent,
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.cfg.OpenDuration)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+func (b *Breaker) remainingOpen() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.advanceLocked(now)
+	if b.state != StateOpen {
+		return 0
+	}
+	return b.openedAt.Add(b.cfg.OpenDuration).Sub(now)
+}
+
+// allow decides whether a call may proceed and whether it is a half-open probe.
+func (b *Breaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advanceLocked(time.Now())
+
+	switch b.state {
+	case StateOpen:
+		return false, ErrOpen
+	case StateHalfOpen:
+		if b.probesInFlight+b.probeSuccesses >= b.cfg.HalfOpenProbes {
+			return false, ErrOpen
+		}
+		b.probesInFlight++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (b *Breaker) record(probe, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe && b.probesInFlight > 0 {
+		b.probesInFlight--
+	}
+
+	if failed {
+		b.failures++
+		if b.state == StateHalfOpen || b.failures >= b.cfg.FailureThreshold {
+			b.openLocked(time.Now())
+		}
+		return
+	}
+
+	b.failures = 0
+	if b.state == StateHalfOpen && probe {
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.cfg.HalfOpenProbes {
+			b.state = StateClosed
+			b.probeSuccesses = 0
+		}
+	}
+}
+
+func (b *Breaker) openLocked(now time.Time) {
+	b.state = StateOpen
+	b.openedAt = now
+	b.probeSuccesses = 0
+}
+
+// advanceLocked moves an open breaker to half-open once OpenDuration has passed.
+func (b *Breaker) advanceLocked(now time.Time) {
+	if b.state == StateOpen && !now.Before(b.openedAt.Add(b.cfg.OpenDuration)) {
+		b.state = StateHalfOpen
+		b.probesInFlight = 0
+		b.probeSuccesses = 0
+	}
+}
@@ -0,0 +1,232 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errDependency = errors.New("dependency failed")
+
+const testOpenDuration = 20 * time.Millisecond
+
+func newTestBreaker(probes int) *Breaker {
+	return New("test", Config{
+		FailureThreshold: 2,
+		OpenDuration:     testOpenDuration,
+		HalfOpenProbes:   probes,
+		MaxConcurrent:    2,
+	})
+}
+
+func fail() error    { return errDependency }
+func succeed() error { return nil }
+
+func TestBreakerTransitions(t *testing.T) {
+	b := newTestBreaker(1)
+	ctx := context.Background()
+
+	if err := b.Do(ctx, fail, nil); !errors.Is(err, errDependency) {
+		t.Fatalf("first failure: err = %v", err)
+	}
+	if got := b.Status().State; got != StateClosed {
+		t.Fatalf("after one failure: state = %s, want %s", got, StateClosed)
+	}
+
+	b.Do(ctx, fail, nil)
+	if got := b.Status().State; got != StateOpen {
+		t.Fatalf("after threshold: state = %s, want %s", got, StateOpen)
+	}
+	if !b.IsOpen() {
+		t.Error("IsOpen = false while open")
+	}
+
+	called := false
+	err := b.Do(ctx, func() error { called = true; return nil }, nil)
+	if !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("while open: err = %v, called = %v; want ErrOpen without a call", err, called)
+	}
+
+	time.Sleep(testOpenDuration + 10*time.Millisecond)
+	if got := b.Status().State; got != StateHalfOpen {
+		t.Fatalf("after open duration: state = %s, want %s", got, StateHalfOpen)
+	}
+	if b.IsOpen() {
+		t.Error("IsOpen = true while half-open")
+	}
+
+	if err := b.Do(ctx, succeed, nil); err != nil {
+		t.Fatalf("probe: err = %v", err)
+	}
+	status := b.Status()
+	if status.State != StateClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("after successful probe: state = %s, failures = %d; want %s, 0",
+			status.State, status.ConsecutiveFailures, StateClosed)
+	}
+	if status.OpenedAt != nil || status.RetryAt != nil {
+		t.Error("closed breaker reports open times")
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	b := newTestBreaker(1)
+	ctx := context.Background()
+	b.Do(ctx, fail, nil)
+	b.Do(ctx, fail, nil)
+	time.Sleep(testOpenDuration + 10*time.Millisecond)
+
+	if err := b.Do(ctx, fail, nil); !errors.Is(err, errDependency) {
+		t.Fatalf("probe: err = %v", err)
+	}
+	status := b.Status()
+	if status.State != StateOpen {
+		t.Fatalf("after failed probe: state = %s, want %s", status.State, StateOpen)
+	}
+	if status.RetryAt == nil || !status.RetryAt.After(time.Now()) {
+		t.Errorf("RetryAt = %v, want a time in the future", status.RetryAt)
+	}
+}
+
+func TestBreakerHalfOpenProbeLimit(t *testing.T) {
+	b := newTestBreaker(2)
+	ctx := context.Background()
+	b.Do(ctx, fail, nil)
+	b.Do(ctx, fail, nil)
+	time.Sleep(testOpenDuration + 10*time.Millisecond)
+
+	// Hold one probe in flight while a second and third call arrive.
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Do(ctx, func() error { close(started); <-release; return nil }, nil)
+	}()
+	<-started
+
+	if err := b.Do(ctx, succeed, nil); err != nil {
+		t.Fatalf("second probe: err = %v", err)
+	}
+	if got := b.Status().State; got != StateHalfOpen {
+		t.Fatalf("after one of two probes: state = %s, want %s", got, StateHalfOpen)
+	}
+	if err := b.Do(ctx, succeed, nil); !errors.Is(err, ErrOpen) {
+		t.Errorf("call beyond the probe limit: err = %v, want ErrOpen", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first probe: err = %v", err)
+	}
+	if got := b.Status().State; got != StateClosed {
+		t.Errorf("after both probes: state = %s, want %s", got, StateClosed)
+	}
+}
+
+func TestBreakerIsFailure(t *testing.T) {
+	b := newTestBreaker(1)
+	ctx := context.Background()
+	errNotFound := errors.New("not found")
+	ignoreNotFound := func(err error) bool { return !errors.Is(err, errNotFound) }
+
+	for i := 0; i < 3; i++ {
+		err := b.Do(ctx, func() error { return errNotFound }, ignoreNotFound)
+		if !errors.Is(err, errNotFound) {
+			t.Fatalf("call %d: err = %v, want %v", i, err, errNotFound)
+		}
+	}
+	status := b.Status()
+	if status.State != StateClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("state = %s, failures = %d; want %s, 0", status.State, status.ConsecutiveFailures, StateClosed)
+	}
+
+	b.Do(ctx, fail, ignoreNotFound)
+	b.Do(ctx, fail, ignoreNotFound)
+	if got := b.Status().State; got != StateOpen {
+		t.Errorf("after counted failures: state = %s, want %s", got, StateOpen)
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b := newTestBreaker(1)
+	ctx := context.Background()
+	b.Do(ctx, fail, nil)
+	b.Do(ctx, succeed, nil)
+	b.Do(ctx, fail, nil)
+	if got := b.Status().State; got != StateClosed {
+		t.Errorf("state = %s, want %s", got, StateClosed)
+	}
+}
+
+func TestBreakerBulkhead(t *testing.T) {
+	b := New("test", Config{
+		FailureThreshold: 1,
+		OpenDuration:     time.Minute,
+		HalfOpenProbes:   1,
+		MaxConcurrent:    1,
+	})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Do(context.Background(), func() error { close(started); <-release; return nil }, nil)
+	}()
+	<-started
+
+	if got := b.Status().InFlight; got != 1 {
+		t.Errorf("InFlight = %d, want 1", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	called := false
+	err := b.Do(ctx, func() error { called = true; return nil }, nil)
+	if !errors.Is(err, context.DeadlineExceeded) || called {
+		t.Errorf("call over the bulkhead: err = %v, called = %v; want deadline exceeded without a call", err, called)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first call: err = %v", err)
+	}
+	if err := b.Do(context.Background(), succeed, nil); err != nil {
+		t.Errorf("call after the slot is freed: err = %v", err)
+	}
+}
+
+func TestBreakerWait(t *testing.T) {
+	b := newTestBreaker(1)
+	b.Do(context.Background(), fail, nil)
+	b.Do(context.Background(), fail, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait with cancelled ctx: err = %v, want %v", err, context.Canceled)
+	}
+
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: err = %v", err)
+	}
+	if b.IsOpen() {
+		t.Error("IsOpen = true after Wait returned")
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *Breaker
+	ctx := context.Background()
+
+	called := false
+	err := b.Do(ctx, func() error { called = true; return errDependency }, nil)
+	if !errors.Is(err, errDependency) || !called {
+		t.Errorf("Do: err = %v, called = %v; want fn's error", err, called)
+	}
+	if b.IsOpen() {
+		t.Error("IsOpen = true for a nil breaker")
+	}
+	if err := b.Wait(ctx); err != nil {
+		t.Errorf("Wait: err = %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/RohitGupta-omniful/OMS/services"
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+// AmendOrder changes the hub or lines of an on_hold or new_order order.
+func (h *Handler) AmendOrder(c *gin.Context) {
+	var in models.OrderAmendmentInput
+	if err := c.BindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid request body")})
+		return
+	}
+
+	version, ok := expectedVersion(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, amendment, err := h.AmendmentService.Amend(ctx, c.Param("order_id"), version, in, h.pipeline.validateOrder)
+	var oe *orderError
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "order not found")})
+	case errors.Is(err, services.ErrInvalidAmendment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, err.Error())})
+	case errors.Is(err, services.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, err.Error())})
+	case errors.Is(err, services.ErrVersionConflict) && order == nil:
+		respondVersionConflict(c, err)
+	case errors.Is(err, errIMSUnauthorized):
+		log.Errorf(i18n.Translate(c, "IMS refused credentials while amending order: %v"), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": i18n.Translate(c, errIMSUnauthorized.Error()), "reason_code": reasonCode(err)})
+	case errors.Is(err, errIMSUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": i18n.Translate(c, errIMSUnavailable.Error()), "reason_code": reasonCode(err)})
+	case errors.Is(err, services.ErrTaxUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": i18n.Translate(c, services.ErrTaxUnavailable.Error()), "reason_code": models.ReasonTaxUnavailable})
+	case errors.As(err, &oe):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, err.Error()), "reason_code": oe.Code})
+	case err != nil && order != nil:
+		// The amendment was saved but its stock could not be reserved; the
+		// order is back on hold and retried in the background.
+		c.JSON(http.StatusAccepted, gin.H{"order": order, "amendment": amendment, "warning": i18n.Translate(c, "inventory reservation pending")})
+	case err != nil:
+		log.Errorf(i18n.Translate(c, "failed to amend order: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to amend order")})
+	default:
+		if order.Status == models.OrderStatusOnHold && order.HoldReason == "" && amendment.Changed("hold_reason") {
+			// The order was held for lack of stock and no longer is, so
+			// nothing is waiting to reserve it.
+			h.pipeline.emitOrderCreated(ctx, *order, "")
+		}
+		setETag(c, order)
+		c.JSON(http.StatusOK, gin.H{"order": order, "amendment": amendment})
+	}
+}
+
+// ListAmendments returns the amendments of an order, oldest first.
+func (h *Handler) ListAmendments(c *gin.Context) {
+	amendments, err := h.AmendmentService.History(c.Request.Context(), c.Param("order_id"))
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "order not found")})
+	case err != nil:
+		log.Errorf(i18n.Translate(c, "failed to list amendments: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to list amendments")})
+	default:
+		c.JSON(http.StatusOK, gin.H{"amendments": amendments})
+	}
+}
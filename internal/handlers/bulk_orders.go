@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/i18n"
+)
+
+const defaultBulkMaxOrders = 500
+
+type CreateBulkOrdersRequest struct {
+	Orders         []models.OrderInput   `json:"orders"`
+	ConflictPolicy models.ConflictPolicy `json:"conflict_policy,omitempty"`
+}
+
+type BulkOrderResult struct {
+	Index      int                 `json:"index"`
+	OrderID    string              `json:"order_id"`
+	Status     models.OrderOutcome `json:"status"`
+	ReasonCode string              `json:"reason_code,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// CreateBulkOrders validates and creates up to orders.bulk_max_orders orders
+// inline, reporting the outcome of each one.
+func (h *Handler) CreateBulkOrders(c *gin.Context) {
+	var req CreateBulkOrdersRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid request body")})
+		return
+	}
+	tenantID, ok := requestTenant(c)
+	if !ok {
+		return
+	}
+
+	if req.ConflictPolicy == "" {
+		req.ConflictPolicy = models.DefaultConflictPolicy
+	}
+	if !req.ConflictPolicy.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid conflict_policy")})
+		return
+	}
+
+	maxOrders := config.GetInt(c, "orders.bulk_max_orders")
+	if maxOrders <= 0 {
+		maxOrders = defaultBulkMaxOrders
+	}
+
+	if len(req.Orders) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "orders must not be empty")})
+		return
+	}
+	if len(req.Orders) > maxOrders {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      i18n.Translate(c, "too many orders in request"),
+			"max_orders": maxOrders,
+		})
+		return
+	}
+
+	// Orders default to the authenticated tenant; other tenants' orders are
+	// rejected below.
+	for i := range req.Orders {
+		if req.Orders[i].TenantID == 0 {
+			req.Orders[i].TenantID = tenantID
+		}
+	}
+	h.pipeline.Prefetch(c.Request.Context(), req.Orders)
+
+	opts := processOptions{ConflictPolicy: req.ConflictPolicy}
+	results := make([]BulkOrderResult, 0, len(req.Orders))
+	var counts models.BulkJobCounts
+	for i, in := range req.Orders {
+		var (
+			order   models.Order
+			outcome models.OrderOutcome
+			err     error
+		)
+		if in.TenantID != tenantID {
+			outcome, err = models.OrderOutcomeRejected, errTenantMismatch
+		} else {
+			order, outcome, err = h.pipeline.Process(c.Request.Context(), in, opts)
+		}
+		result := BulkOrderResult{Index: i, OrderID: in.OrderID, Status: outcome, ReasonCode: order.HoldReason}
+		if err != nil {
+			result.ReasonCode = reasonCode(err)
+			result.Error = err.Error()
+		}
+		counts.Add(outcome)
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"created":  counts.CreatedCount,
+		"updated":  counts.UpdatedCount,
+		"skipped":  counts.SkippedCount,
+		"rejected": counts.RejectedCount,
+		"results":  results,
+	})
+}
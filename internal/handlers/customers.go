@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RohitGupta-omniful/OMS/services"
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+// GetCustomer returns a customer's contact details and default address.
+func (h *Handler) GetCustomer(c *gin.Context) {
+	tenantID, ok := requestTenant(c)
+	if !ok {
+		return
+	}
+	customer, err := h.CustomerService.Get(c.Request.Context(), tenantID, c.Param("customer_id"))
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "customer not found")})
+	case err != nil:
+		log.Errorf(i18n.Translate(c, "failed to load customer: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to load customer")})
+	default:
+		c.JSON(http.StatusOK, customer)
+	}
+}
+
+// ListCustomerOrders returns a customer's orders, newest first. The limit
+// and offset query parameters page through them and status filters them.
+func (h *Handler) ListCustomerOrders(c *gin.Context) {
+	tenantID, ok := requestTenant(c)
+	if !ok {
+		return
+	}
+	limit, offset := services.DefaultCustomerOrdersLimit, 0
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > services.MaxCustomerOrdersLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "limit must be between 1 and 200")})
+			return
+		}
+		limit = n
+	}
+	if value := c.Query("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "offset must not be negative")})
+			return
+		}
+		offset = n
+	}
+
+	customerID := c.Param("customer_id")
+	orders, err := h.CustomerService.ListOrders(c.Request.Context(), tenantID, customerID, c.Query("status"), limit, offset)
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "customer not found")})
+	case err != nil:
+		log.Errorf(i18n.Translate(c, "failed to list customer orders: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to list customer orders")})
+	default:
+		c.JSON(http.StatusOK, gin.H{"customer_id": customerID, "orders": orders, "limit": limit, "offset": offset})
+	}
+}
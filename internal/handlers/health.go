@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/RohitGupta-omniful/OMS/internal/breaker"
+	"github.com/gin-gonic/gin"
+)
+
+// Health reports the state of the circuit breakers guarding IMS and the
+// inventory service. The service is degraded while any breaker is not closed.
+func (h *Handler) Health(c *gin.Context) {
+	status := "ok"
+	breakers := make([]breaker.Status, 0, len(h.Breakers))
+	for _, b := range h.Breakers {
+		s := b.Status()
+		if s.State != breaker.StateClosed {
+			status = "degraded"
+		}
+		breakers = append(breakers, s)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status, "breakers": breakers})
+}
@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/internal/xlsx"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/omniful/go_commons/csv"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+type fileFormat string
+
+const (
+	formatCSV    fileFormat = "csv"
+	formatXLSX   fileFormat = "xlsx"
+	formatJSON   fileFormat = "json"
+	formatNDJSON fileFormat = "ndjson"
+)
+
+const orderFileBatchSize = 100
+
+// detectFileFormat decides how an uploaded order file should be parsed, using
+// the object key's extension first and falling back to its content type.
+func detectFileFormat(key, contentType string) fileFormat {
+	switch strings.ToLower(filepath.Ext(key)) {
+	case ".xlsx":
+		return formatXLSX
+	case ".json":
+		return formatJSON
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	case ".csv":
+		return formatCSV
+	}
+
+	contentType = strings.ToLower(contentType)
+	switch {
+	case strings.HasPrefix(contentType, xlsx.ContentType):
+		return formatXLSX
+	case strings.HasPrefix(contentType, "application/x-ndjson"), strings.HasPrefix(contentType, "application/jsonl"):
+		return formatNDJSON
+	case strings.HasPrefix(contentType, "application/json"):
+		return formatJSON
+	}
+	return formatCSV
+}
+
+// orderRecord is one order read from a bulk file. Row or Raw keeps the
+// original input so rejected records can be reported back unchanged.
+type orderRecord struct {
+	Input    models.OrderInput
+	ParseErr error
+	Row      []string
+	Raw      json.RawMessage
+}
+
+// orderRecordReader is the common view of an order file regardless of format.
+type orderRecordReader interface {
+	IsEOF() bool
+	ReadNextBatch() ([]orderRecord, error)
+	Close() error
+}
+
+// newOrderRecordReader opens filePath in the given format. Headers are only
+// returned for tabular formats.
+func newOrderRecordReader(ctx context.Context, format fileFormat, filePath, sheet string) (orderRecordReader, []string, error) {
+	switch format {
+	case formatJSON, formatNDJSON:
+		f, err := os.Open(filePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if format == formatNDJSON {
+			return newNDJSONRecordReader(f), nil, nil
+		}
+		r, err := newJSONArrayRecordReader(f)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return r, nil, nil
+	}
+
+	rows, err := newOrderRowReader(ctx, format, filePath, sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+	headers, err := rows.GetHeaders()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	colIdx := make(map[string]int)
+	for i, col := range headers {
+		colIdx[col] = i
+	}
+	return &tabularRecordReader{rows: rows, colIdx: colIdx}, headers, nil
+}
+
+// orderRowReader is the common view of a CSV or spreadsheet order file.
+type orderRowReader interface {
+	GetHeaders() ([]string, error)
+	IsEOF() bool
+	ReadNextBatch() ([][]string, error)
+}
+
+func newOrderRowReader(ctx context.Context, format fileFormat, filePath, sheet string) (orderRowReader, error) {
+	if format == formatXLSX {
+		r, err := xlsx.NewReader(filePath, sheet, orderFileBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		return &sanitizingRowReader{reader: r}, nil
+	}
+
+	csvReader, err := csv.NewCommonCSV(
+		csv.WithBatchSize(orderFileBatchSize),
+		csv.WithSource(csv.Local),
+		csv.WithLocalFileInfo(filePath),
+		csv.WithHeaderSanitizers(csv.SanitizeAsterisks, csv.SanitizeToLower),
+		csv.WithDataRowSanitizers(csv.SanitizeSpace),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err = csvReader.InitializeReader(ctx); err != nil {
+		return nil, err
+	}
+
+	return &csvRowReader{
+		getHeaders: func() ([]string, error) {
+			headers, err := csvReader.GetHeaders()
+			if err != nil {
+				return nil, err
+			}
+			out := make([]string, 0, len(headers))
+			for _, h := range headers {
+				out = append(out, h)
+			}
+			return out, nil
+		},
+		isEOF: csvReader.IsEOF,
+		readNextBatch: func() ([][]string, error) {
+			records, err := csvReader.ReadNextBatch()
+			if err != nil {
+				return nil, err
+			}
+			rows := make([][]string, 0, len(records))
+			for _, rec := range records {
+				rows = append(rows, rec)
+			}
+			return rows, nil
+		},
+	}, nil
+}
+
+// csvRowReader adapts the go_commons CSV reader to orderRowReader.
+type csvRowReader struct {
+	getHeaders    func() ([]string, error)
+	isEOF         func() bool
+	readNextBatch func() ([][]string, error)
+}
+
+func (r *csvRowReader) GetHeaders() ([]string, error)      { return r.getHeaders() }
+func (r *csvRowReader) IsEOF() bool                        { return r.isEOF() }
+func (r *csvRowReader) ReadNextBatch() ([][]string, error) { return r.readNextBatch() }
+
+// sanitizingRowReader applies the same header and cell clean-up to spreadsheet
+// rows that the CSV reader applies through its sanitizers.
+type sanitizingRowReader struct {
+	reader orderRowReader
+}
+
+func (r *sanitizingRowReader) GetHeaders() ([]string, error) {
+	headers, err := r.reader.GetHeaders()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(headers))
+	for i, h := range headers {
+		out[i] = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(h, "*", "")))
+	}
+	return out, nil
+}
+
+func (r *sanitizingRowReader) IsEOF() bool { return r.reader.IsEOF() }
+
+func (r *sanitizingRowReader) ReadNextBatch() ([][]string, error) {
+	rows, err := r.reader.ReadNextBatch()
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		for i, v := range row {
+			row[i] = strings.TrimSpace(v)
+		}
+	}
+	return rows, nil
+}
+
+// tabularRecordReader turns CSV or spreadsheet rows into order records.
+type tabularRecordReader struct {
+	rows   orderRowReader
+	colIdx map[string]int
+}
+
+func (r *tabularRecordReader) IsEOF() bool  { return r.rows.IsEOF() }
+func (r *tabularRecordReader) Close() error { return nil }
+
+func (r *tabularRecordReader) ReadNextBatch() ([]orderRecord, error) {
+	rows, err := r.rows.ReadNextBatch()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]orderRecord, 0, len(rows))
+	for _, row := range rows {
+		in, err := orderInputFromRow(row, r.colIdx)
+		records = append(records, orderRecord{Input: in, ParseErr: err, Row: row})
+	}
+	return records, nil
+}
+
+// jsonArrayRecordReader streams the elements of a top-level JSON array. The
+// closing bracket is read after the last element, so a truncated file is an
+// error rather than a shorter list of orders.
+type jsonArrayRecordReader struct {
+	file *os.File
+	dec  *json.Decoder
+	eof  bool
+}
+
+func newJSONArrayRecordReader(f *os.File) (*jsonArrayRecordReader, error) {
+	dec := json.NewDecoder(f)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("JSON order file must contain an array of orders")
+	}
+	return &jsonArrayRecordReader{file: f, dec: dec}, nil
+}
+
+func (r *jsonArrayRecordReader) IsEOF() bool  { return r.eof }
+func (r *jsonArrayRecordReader) Close() error { return r.file.Close() }
+
+func (r *jsonArrayRecordReader) ReadNextBatch() ([]orderRecord, error) {
+	records := make([]orderRecord, 0, orderFileBatchSize)
+	for len(records) < orderFileBatchSize {
+		if !r.dec.More() {
+			r.eof = true
+			return records, r.readEnd()
+		}
+		var raw json.RawMessage
+		if err := r.dec.Decode(&raw); err != nil {
+			r.eof = true
+			return records, err
+		}
+		records = append(records, decodeOrderRecord(raw))
+	}
+	return records, nil
+}
+
+// readEnd reads the closing bracket of the array and checks nothing follows.
+func (r *jsonArrayRecordReader) readEnd() error {
+	tok, err := r.dec.Token()
+	if errors.Is(err, io.EOF) {
+		return errors.New("JSON order file ends before the closing ] of its array")
+	}
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != ']' {
+		return errors.New("JSON order file must contain an array of orders")
+	}
+	if _, err := r.dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("JSON order file has data after its array")
+	}
+	return nil
+}
+
+// ndjsonRecordReader reads one order object per line.
+type ndjsonRecordReader struct {
+	file    *os.File
+	scanner *bufio.Scanner
+	eof     bool
+}
+
+func newNDJSONRecordReader(f *os.File) *ndjsonRecordReader {
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &ndjsonRecordReader{file: f, scanner: scanner}
+}
+
+func (r *ndjsonRecordReader) IsEOF() bool  { return r.eof }
+func (r *ndjsonRecordReader) Close() error { return r.file.Close() }
+
+func (r *ndjsonRecordReader) ReadNextBatch() ([]orderRecord, error) {
+	records := make([]orderRecord, 0, orderFileBatchSize)
+	for len(records) < orderFileBatchSize {
+		if !r.scanner.Scan() {
+			r.eof = true
+			return records, r.scanner.Err()
+		}
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		records = append(records, decodeOrderRecord(append(json.RawMessage(nil), line...)))
+	}
+	return records, nil
+}
+
+func decodeOrderRecord(raw json.RawMessage) orderRecord {
+	rec := orderRecord{Raw: raw}
+	if err := json.Unmarshal(raw, &rec.Input); err != nil {
+		rec.ParseErr = errInvalidOrderData
+	}
+	return rec
+}
+
+// writeRejectedRecords saves records that failed validation in the same format
+// as the uploaded file and returns the path of the report.
+func writeRejectedRecords(ctx context.Context, format fileFormat, headers []string, records []orderRecord) (string, error) {
+	logger := log.DefaultLogger()
+	timestamp := time.Now().Format("20060102_150405")
+	filePath := "public/invalid_orders_" + timestamp + "." + string(format)
+
+	switch format {
+	case formatJSON, formatNDJSON:
+		var buf bytes.Buffer
+		if format == formatJSON {
+			buf.WriteString("[\n")
+		}
+		for i, rec := range records {
+			if format == formatJSON && i > 0 {
+				buf.WriteString(",\n")
+			}
+			buf.Write(rec.Raw)
+			if format == formatNDJSON {
+				buf.WriteString("\n")
+			}
+		}
+		if format == formatJSON {
+			buf.WriteString("\n]\n")
+		}
+
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filePath, buf.Bytes(), 0o644); err != nil {
+			logger.Errorf(i18n.Translate(ctx, "failed to write invalid rows: %v"), err)
+			return "", err
+		}
+		return filePath, nil
+
+	case formatXLSX:
+		rows := make([][]string, 0, len(records))
+		for _, rec := range records {
+			rows = append(rows, rec.Row)
+		}
+		if err := xlsx.WriteFile(filePath, "invalid_orders", headers, rows); err != nil {
+			logger.Errorf(i18n.Translate(ctx, "failed to write invalid rows: %v"), err)
+			return "", err
+		}
+		return filePath, nil
+	}
+
+	dest := &csv.Destination{}
+	dest.SetFileName(filePath)
+	dest.SetUploadDirectory("public/")
+	dest.SetRandomizedFileName(false)
+
+	writer, err := csv.NewCommonCSVWriter(
+		csv.WithWriterHeaders(headers),
+		csv.WithWriterDestination(*dest),
+	)
+	if err != nil {
+		logger.Errorf(i18n.Translate(ctx, "failed to create CSV writer: %v"), err)
+		return "", err
+	}
+	defer writer.Close(ctx)
+
+	if err := writer.Initialize(); err != nil {
+		logger.Errorf(i18n.Translate(ctx, "failed to initialize CSV writer: %v"), err)
+		return "", err
+	}
+
+	var invalid csv.Records
+	for _, rec := range records {
+		invalid = append(invalid, rec.Row)
+	}
+	if err := writer.WriteNextBatch(invalid); err != nil {
+		logger.Errorf(i18n.Translate(ctx, "failed to write invalid rows: %v"), err)
+		return "", err
+	}
+
+	return filePath, nil
+}
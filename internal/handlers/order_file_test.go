@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/RohitGupta-omniful/OMS/internal/xlsx"
+)
+
+func TestDetectFileFormat(t *testing.T) {
+	tests := []struct {
+		key         string
+		contentType string
+		want        fileFormat
+	}{
+		{"orders.xlsx", "", formatXLSX},
+		{"uploads/ORDERS.XLSX", "text/csv", formatXLSX},
+		{"orders.json", "", formatJSON},
+		{"orders.ndjson", "application/json", formatNDJSON},
+		{"orders.jsonl", "", formatNDJSON},
+		{"orders.csv", xlsx.ContentType, formatCSV},
+		{"orders", xlsx.ContentType, formatXLSX},
+		{"orders", "application/json; charset=utf-8", formatJSON},
+		{"orders", "application/x-ndjson", formatNDJSON},
+		{"orders", "application/jsonl", formatNDJSON},
+		{"orders", "text/csv", formatCSV},
+		{"orders", "", formatCSV},
+	}
+	for _, tt := range tests {
+		if got := detectFileFormat(tt.key, tt.contentType); got != tt.want {
+			t.Errorf("detectFileFormat(%q, %q) = %q, want %q", tt.key, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestOrderRowReaderXLSX(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "orders.xlsx")
+	headers := []string{"Order_ID*", " SKU_ID* ", "Quantity"}
+	rows := [][]string{
+		{"ORD-1", " SKU-1 ", "2"},
+		{"ORD-2", "SKU-2", "1"},
+		{"ORD-3", "SKU-3"},
+	}
+	if err := xlsx.WriteFile(filePath, "orders", headers, rows); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	reader, err := newOrderRowReader(context.Background(), formatXLSX, filePath, "")
+	if err != nil {
+		t.Fatalf("newOrderRowReader: %v", err)
+	}
+
+	gotHeaders, err := reader.GetHeaders()
+	if err != nil {
+		t.Fatalf("GetHeaders: %v", err)
+	}
+	if want := []string{"order_id", "sku_id", "quantity"}; !reflect.DeepEqual(gotHeaders, want) {
+		t.Errorf("headers = %q, want %q", gotHeaders, want)
+	}
+
+	var got [][]string
+	for !reader.IsEOF() {
+		batch, err := reader.ReadNextBatch()
+		if err != nil {
+			t.Fatalf("ReadNextBatch: %v", err)
+		}
+		got = append(got, batch...)
+	}
+	want := [][]string{
+		{"ORD-1", "SKU-1", "2"},
+		{"ORD-2", "SKU-2", "1"},
+		{"ORD-3", "SKU-3", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+}
+
+// writeOrderFile writes body to a file named name under t's temp directory.
+func writeOrderFile(t *testing.T, name, body string) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filePath, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+// readAllRecords drains r, failing t on any read error.
+func readAllRecords(t *testing.T, r orderRecordReader) []orderRecord {
+	t.Helper()
+	var records []orderRecord
+	for !r.IsEOF() {
+		batch, err := r.ReadNextBatch()
+		if err != nil {
+			t.Fatalf("ReadNextBatch: %v", err)
+		}
+		records = append(records, batch...)
+	}
+	return records
+}
+
+func TestOrderRecordReaderJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  fileFormat
+		body    string
+		want    []string
+		wantErr []bool
+	}{
+		{
+			name:    "json array",
+			format:  formatJSON,
+			body:    `[{"order_id":"ORD-1","quantity":2},{"order_id":"ORD-2","quantity":"two"}]`,
+			want:    []string{"ORD-1", "ORD-2"},
+			wantErr: []bool{false, true},
+		},
+		{
+			name:   "empty json array",
+			format: formatJSON,
+			body:   ` [ ] `,
+		},
+		{
+			name:    "ndjson with blank lines",
+			format:  formatNDJSON,
+			body:    "{\"order_id\":\"ORD-1\"}\n\n  \n{\"order_id\":\"ORD-2\"}\nnot json\n",
+			want:    []string{"ORD-1", "ORD-2", ""},
+			wantErr: []bool{false, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := writeOrderFile(t, "orders."+string(tt.format), tt.body)
+			r, headers, err := newOrderRecordReader(context.Background(), tt.format, filePath, "")
+			if err != nil {
+				t.Fatalf("newOrderRecordReader: %v", err)
+			}
+			defer r.Close()
+			if headers != nil {
+				t.Errorf("headers = %q, want none", headers)
+			}
+
+			records := readAllRecords(t, r)
+			if len(records) != len(tt.want) {
+				t.Fatalf("read %d records, want %d", len(records), len(tt.want))
+			}
+			for i, rec := range records {
+				if rec.Input.OrderID != tt.want[i] {
+					t.Errorf("record %d: order_id = %q, want %q", i, rec.Input.OrderID, tt.want[i])
+				}
+				if gotErr := rec.ParseErr != nil; gotErr != tt.wantErr[i] {
+					t.Errorf("record %d: ParseErr = %v, want error %v", i, rec.ParseErr, tt.wantErr[i])
+				}
+				if tt.wantErr[i] && !errors.Is(rec.ParseErr, errInvalidOrderData) {
+					t.Errorf("record %d: ParseErr = %v, want %v", i, rec.ParseErr, errInvalidOrderData)
+				}
+				if len(rec.Raw) == 0 {
+					t.Errorf("record %d: raw input not kept", i)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderRecordReaderJSONBatches(t *testing.T) {
+	var body []byte
+	body = append(body, '[')
+	for i := 0; i < orderFileBatchSize+1; i++ {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, `{"order_id":"ORD"}`...)
+	}
+	body = append(body, ']')
+
+	filePath := writeOrderFile(t, "orders.json", string(body))
+	r, _, err := newOrderRecordReader(context.Background(), formatJSON, filePath, "")
+	if err != nil {
+		t.Fatalf("newOrderRecordReader: %v", err)
+	}
+	defer r.Close()
+
+	first, err := r.ReadNextBatch()
+	if err != nil {
+		t.Fatalf("ReadNextBatch: %v", err)
+	}
+	if len(first) != orderFileBatchSize || r.IsEOF() {
+		t.Fatalf("first batch = %d records, IsEOF = %v; want %d, false", len(first), r.IsEOF(), orderFileBatchSize)
+	}
+	second, err := r.ReadNextBatch()
+	if err != nil {
+		t.Fatalf("ReadNextBatch: %v", err)
+	}
+	if len(second) != 1 || !r.IsEOF() {
+		t.Errorf("second batch = %d records, IsEOF = %v; want 1, true", len(second), r.IsEOF())
+	}
+}
+
+func TestOrderRecordReaderJSONNotArray(t *testing.T) {
+	for _, body := range []string{`{"order_id":"ORD-1"}`, ``, `"orders"`} {
+		filePath := writeOrderFile(t, "orders.json", body)
+		if r, _, err := newOrderRecordReader(context.Background(), formatJSON, filePath, ""); err == nil {
+			r.Close()
+			t.Errorf("newOrderRecordReader(%q): expected an error", body)
+		}
+	}
+}
+
+func TestOrderRecordReaderJSONTruncated(t *testing.T) {
+	for _, body := range []string{
+		`[{"order_id":"ORD-1"}`,
+		`[{"order_id":"ORD-1"},`,
+		`[{"order_id":"ORD-1"},{"order_id":`,
+		`[{"order_id":"ORD-1"}] [`,
+	} {
+		filePath := writeOrderFile(t, "orders.json", body)
+		r, _, err := newOrderRecordReader(context.Background(), formatJSON, filePath, "")
+		if err != nil {
+			t.Fatalf("newOrderRecordReader(%q): %v", body, err)
+		}
+
+		var readErr error
+		for !r.IsEOF() && readErr == nil {
+			_, readErr = r.ReadNextBatch()
+		}
+		r.Close()
+		if readErr == nil {
+			t.Errorf("reading %q: expected an error", body)
+		}
+	}
+}
+
+func TestOrderRecordReaderXLSXKeepsCase(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "orders.xlsx")
+	headers := []string{"Tenant_ID*", "Order_ID*", "Customer_Name", "SKU_ID*", "Quantity*", "Price*", "Shipping_City", "Customer_Email"}
+	rows := [][]string{{"7", "ORD-1", "Jane Doe", "SKU-A", "2", "10", "Dubai", "Jane@Example.com"}}
+	if err := xlsx.WriteFile(filePath, "orders", headers, rows); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	r, _, err := newOrderRecordReader(context.Background(), formatXLSX, filePath, "")
+	if err != nil {
+		t.Fatalf("newOrderRecordReader: %v", err)
+	}
+	defer r.Close()
+
+	records := readAllRecords(t, r)
+	if len(records) != 1 || records[0].ParseErr != nil {
+		t.Fatalf("records = %+v, want one valid record", records)
+	}
+	in := records[0].Input
+	if in.OrderID != "ord-1" || in.SKUID != "sku-a" {
+		t.Errorf("identifiers = %q, %q; want them lower-cased", in.OrderID, in.SKUID)
+	}
+	if in.CustomerName != "Jane Doe" || in.CustomerEmail != "Jane@Example.com" {
+		t.Errorf("customer = %q, %q; want the case kept", in.CustomerName, in.CustomerEmail)
+	}
+	if in.ShippingAddress == nil || in.ShippingAddress.City != "Dubai" {
+		t.Errorf("shipping address = %+v, want city Dubai", in.ShippingAddress)
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// CreateOrder validates and creates a single order. Requests carrying an
+// Idempotency-Key header can be retried safely: a repeat of a completed
+// request returns the order created the first time.
+func (h *Handler) CreateOrder(c *gin.Context) {
+	var in models.OrderInput
+	if err := c.BindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid request body")})
+		return
+	}
+	tenantID, ok := claimedTenant(c, in.TenantID)
+	if !ok {
+		return
+	}
+	in.TenantID = tenantID
+
+	ctx := c.Request.Context()
+	key := strings.TrimSpace(c.GetHeader(idempotencyKeyHeader))
+
+	if key != "" {
+		requestHash := hashOrderInput(in)
+		existing, err := h.IdempotencyService.Reserve(ctx, in.TenantID, key, requestHash)
+		if err != nil {
+			log.Errorf(i18n.Translate(c, "failed to reserve idempotency key: %v"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to create order")})
+			return
+		}
+		if existing != nil {
+			h.replayCreateOrder(c, existing, requestHash)
+			return
+		}
+	}
+
+	order, _, err := h.pipeline.Process(ctx, in, processOptions{ConflictPolicy: models.ConflictRejectDuplicate})
+	if err != nil {
+		if key != "" {
+			if rerr := h.IdempotencyService.Release(ctx, in.TenantID, key); rerr != nil {
+				log.Errorf(i18n.Translate(c, "failed to release idempotency key: %v"), rerr)
+			}
+		}
+		if errors.Is(err, errOrderNotSaved) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to create order")})
+			return
+		}
+		if errors.Is(err, errIMSUnauthorized) {
+			log.Errorf(i18n.Translate(c, "IMS refused credentials while creating order: %v"), err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": i18n.Translate(c, errIMSUnauthorized.Error()), "reason_code": reasonCode(err)})
+			return
+		}
+		if errors.Is(err, errIMSUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": i18n.Translate(c, errIMSUnavailable.Error()), "reason_code": reasonCode(err)})
+			return
+		}
+		if errors.Is(err, errTaxUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": i18n.Translate(c, errTaxUnavailable.Error()), "reason_code": reasonCode(err)})
+			return
+		}
+		if errors.Is(err, errDuplicateOrder) {
+			c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, err.Error()), "reason_code": reasonCode(err)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, err.Error()), "reason_code": reasonCode(err)})
+		return
+	}
+
+	if key != "" {
+		if err := h.IdempotencyService.Complete(ctx, in.TenantID, key, order.OrderID); err != nil {
+			log.Errorf(i18n.Translate(c, "failed to complete idempotency key: %v"), err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+func (h *Handler) replayCreateOrder(c *gin.Context, existing *models.IdempotencyRecord, requestHash string) {
+	if existing.RequestHash != requestHash {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": i18n.Translate(c, "Idempotency-Key was already used with a different request body")})
+		return
+	}
+	if existing.Status != models.IdempotencyStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, "a request with this Idempotency-Key is still in progress")})
+		return
+	}
+
+	order, err := h.OrderService.GetTenantOrder(c.Request.Context(), existing.TenantID, existing.OrderID)
+	if err != nil {
+		log.Errorf(i18n.Translate(c, "failed to load order %s for idempotent replay: %v"), existing.OrderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to load order")})
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.JSON(http.StatusCreated, order)
+}
+
+func hashOrderInput(in models.OrderInput) string {
+	body, _ := json.Marshal(in)
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
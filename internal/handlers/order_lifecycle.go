@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/RohitGupta-omniful/OMS/services"
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+// GetOrder returns an order with its version as the ETag.
+func (h *Handler) GetOrder(c *gin.Context) {
+	order, err := h.OrderService.GetOrder(c.Request.Context(), c.Param("order_id"))
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "order not found")})
+	case err != nil:
+		log.Errorf(i18n.Translate(c, "failed to load order: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to load order")})
+	default:
+		setETag(c, order)
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// ConfirmOrder confirms a reserved order so its reservation no longer expires.
+func (h *Handler) ConfirmOrder(c *gin.Context) {
+	version, ok := expectedVersion(c)
+	if !ok {
+		return
+	}
+	order, err := h.ReservationService.Confirm(c.Request.Context(), c.Param("order_id"), version)
+	h.respondTransition(c, order, err)
+}
+
+// ShipOrder ships everything allocated to an order that has not shipped yet
+// as a single shipment and commits the stock.
+func (h *Handler) ShipOrder(c *gin.Context) {
+	version, ok := expectedVersion(c)
+	if !ok {
+		return
+	}
+	_, order, err := h.ShipmentService.ShipRemaining(c.Request.Context(), c.Param("order_id"), version, models.ShipmentInput{})
+	h.respondTransition(c, order, err)
+}
+
+// CancelOrder cancels an order that has not shipped and releases its stock
+// reservation.
+func (h *Handler) CancelOrder(c *gin.Context) {
+	version, ok := expectedVersion(c)
+	if !ok {
+		return
+	}
+	order, err := h.ReservationService.Cancel(c.Request.Context(), c.Param("order_id"), version)
+	h.respondTransition(c, order, err)
+}
+
+func (h *Handler) respondTransition(c *gin.Context, order *models.Order, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "order not found")})
+	case errors.Is(err, services.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, err.Error())})
+	case errors.Is(err, services.ErrVersionConflict) && order == nil:
+		respondVersionConflict(c, err)
+	case err != nil && order != nil:
+		// The order changed state but the inventory service call failed.
+		log.Errorf(i18n.Translate(c, "failed to settle reservation for order %s: %v"), order.OrderID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": i18n.Translate(c, "inventory update failed, retry later"), "order": order})
+	case err != nil:
+		log.Errorf(i18n.Translate(c, "failed to update order: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to update order")})
+	default:
+		setETag(c, order)
+		c.JSON(http.StatusOK, order)
+	}
+}
@@ -0,0 +1,796 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/IMS_APIS"
+	"github.com/RohitGupta-omniful/OMS/internal/allocation"
+	"github.com/RohitGupta-omniful/OMS/kafka"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/RohitGupta-omniful/OMS/services"
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+// orderError is a pipeline failure carrying a reason code for job reports
+// and API responses.
+type orderError struct {
+	Code    string
+	Message string
+}
+
+func (e *orderError) Error() string { return e.Message }
+
+// childOrderIDSeparator joins a split order's ID and a child's number. It is
+// rejected in submitted order IDs, so a child ID can never clash with one.
+const childOrderIDSeparator = "~"
+
+var (
+	errInvalidOrderData  = &orderError{Code: models.ReasonInvalidData, Message: "invalid order_id, quantity, price or tenant_id"}
+	errMissingHubOrSKU   = &orderError{Code: models.ReasonMissingHubOrSKU, Message: "hub_id and sku_id are required"}
+	errInvalidHub        = &orderError{Code: models.ReasonInvalidHub, Message: "invalid hub_id"}
+	errSKUNotOnHub       = &orderError{Code: models.ReasonSKUNotOnHub, Message: "sku_id is not stocked at hub_id"}
+	errInsufficientStock = &orderError{Code: models.ReasonInsufficientStock, Message: "hub_id does not have enough stock of sku_id"}
+	errOrderNotSaved     = &orderError{Code: models.ReasonSaveFailed, Message: "failed to save order"}
+	errDuplicateOrder    = &orderError{Code: models.ReasonDuplicateOrder, Message: "order_id already exists"}
+	errInvalidFulfilment = &orderError{Code: models.ReasonInvalidData, Message: "invalid fulfilment_policy"}
+	errNoHubAvailable    = &orderError{Code: models.ReasonNoHubAvailable, Message: "no hub could be allocated for the order"}
+	errIMSUnavailable    = &orderError{Code: models.ReasonIMSUnavailable, Message: "IMS is unavailable, try again later"}
+	errIMSUnauthorized   = &orderError{Code: models.ReasonIMSUnauthorized, Message: "IMS refused OMS's credentials"}
+	errInvalidDates      = &orderError{Code: models.ReasonInvalidData, Message: "order_date and promise_date must be dates, with promise_date not before order_date"}
+	errInvalidCurrency   = &orderError{Code: models.ReasonInvalidPricing, Message: "currency must be an ISO 4217 currency code"}
+	errInvalidAmount     = &orderError{Code: models.ReasonInvalidPricing, Message: "discount, tax and shipping must be numbers"}
+	errDiscountTooLarge  = &orderError{Code: models.ReasonInvalidPricing, Message: "a line discount must not exceed quantity times price"}
+	errTaxUnavailable    = &orderError{Code: models.ReasonTaxUnavailable, Message: services.ErrTaxUnavailable.Error()}
+	errTenantMismatch    = &orderError{Code: models.ReasonTenantMismatch, Message: "tenant_id does not match the authenticated tenant"}
+	errReservedOrderID   = &orderError{Code: models.ReasonInvalidData, Message: "order_id must not contain " + childOrderIDSeparator}
+)
+
+// reasonCode extracts the reason code from a pipeline error.
+func reasonCode(err error) string {
+	var oe *orderError
+	if errors.As(err, &oe) {
+		return oe.Code
+	}
+	return ""
+}
+
+const orderCreatedEvent = "order.created"
+
+// insufficientStockAction decides whether an order whose hub lacks stock is
+// held (the default) or rejected.
+const (
+	insufficientStockHold   = "hold"
+	insufficientStockReject = "reject"
+)
+
+// orderPipeline validates incoming orders, persists them and emits
+// order.created. Bulk files and the inline bulk API both go through it.
+type orderPipeline struct {
+	OrderService          *services.OrderService
+	EventLogService       *services.EventLogService
+	TenantSettingsService *services.TenantSettingsService
+	CustomerService       *services.CustomerService
+	TaxService            *services.TaxService
+	KafkaProducer         *kafka.Producer
+	Validator             *IMS_APIS.Validator
+	// Allocator chooses the hub of orders submitted without one. Such
+	// orders are rejected when it is nil.
+	Allocator *allocation.Engine
+}
+
+// processOptions carries per-submission settings through the pipeline.
+type processOptions struct {
+	// JobID is set for orders imported from a bulk file; order.created is
+	// emitted at most once per tenant, order and job.
+	JobID string
+	// ConflictPolicy decides what happens when the order_id already exists.
+	ConflictPolicy models.ConflictPolicy
+}
+
+func newOrderPipeline(orderService *services.OrderService, producer *kafka.Producer, validator *IMS_APIS.Validator, allocator *allocation.Engine, taxService *services.TaxService) *orderPipeline {
+	return &orderPipeline{
+		OrderService:          orderService,
+		EventLogService:       services.NewEventLogService(),
+		TenantSettingsService: services.NewTenantSettingsService(),
+		CustomerService:       services.NewCustomerService(),
+		TaxService:            taxService,
+		KafkaProducer:         producer,
+		Validator:             validator,
+		Allocator:             allocator,
+	}
+}
+
+// Prefetch validates the distinct hubs and SKUs of a batch of orders in one
+// go, so Process can check each order against the cache.
+func (p *orderPipeline) Prefetch(ctx context.Context, inputs []models.OrderInput) {
+	hubIDs := make([]string, 0, len(inputs))
+	pairs := make([]IMS_APIS.HubSKU, 0, len(inputs))
+	for _, in := range inputs {
+		hubIDs = append(hubIDs, in.HubID)
+		for _, line := range in.NormalizedLines() {
+			pairs = append(pairs, IMS_APIS.HubSKU{HubID: in.HubID, SKUID: line.SKUID})
+		}
+	}
+	p.Validator.Prefetch(ctx, hubIDs, pairs)
+}
+
+// Process runs a single order through validation, persistence and event
+// emission. Skipped orders return no error; rejected and duplicate orders do.
+// Orders held for lack of stock are saved with HoldReason set and are not
+// emitted.
+func (p *orderPipeline) Process(ctx context.Context, in models.OrderInput, opts processOptions) (models.Order, models.OrderOutcome, error) {
+	logger := log.DefaultLogger()
+
+	if strings.Contains(in.OrderID, childOrderIDSeparator) {
+		logger.Warnf(i18n.Translate(ctx, "order %s rejected: %v"), in.OrderID, errReservedOrderID)
+		return models.Order{}, models.OrderOutcomeRejected, errReservedOrderID
+	}
+
+	normalizeContact(&in)
+	normalizeCurrency(ctx, &in)
+	settings := p.tenantSettings(ctx, in.TenantID)
+
+	policy := opts.ConflictPolicy
+	if policy == "" {
+		policy = models.DefaultConflictPolicy
+	}
+
+	var hubAllocation *models.HubAllocation
+	if in.HubID == "" && p.Allocator != nil && checkOrderData(in) == nil {
+		if len(in.NormalizedLines()) > 1 && splitAllowed(in, settings) {
+			plan, err := p.Allocator.Split(ctx, allocationRequest(in, settings))
+			if err != nil {
+				err = allocationError(err)
+				logger.Warnf(i18n.Translate(ctx, "order %s rejected: %v"), in.OrderID, err)
+				return models.Order{}, models.OrderOutcomeRejected, err
+			}
+			if plan != nil && len(plan.Groups) > 1 {
+				return p.processSplit(ctx, in, plan, settings, policy, opts)
+			}
+		}
+
+		decision, err := p.allocateHub(ctx, in, settings)
+		if err != nil {
+			logger.Warnf(i18n.Translate(ctx, "order %s rejected: %v"), in.OrderID, err)
+			return models.Order{}, models.OrderOutcomeRejected, err
+		}
+		logger.Infof(i18n.Translate(ctx, "order %s allocated to hub %s by %s: %s"), in.OrderID, decision.HubID, decision.Strategy, decision.Reason)
+		in.HubID = decision.HubID
+		hubAllocation = decision
+	}
+
+	holdReason, err := p.validate(ctx, in)
+	if err != nil {
+		logger.Warnf(i18n.Translate(ctx, "order %s rejected: %v"), in.OrderID, err)
+		return models.Order{}, models.OrderOutcomeRejected, err
+	}
+
+	fulfilment, err := fulfilmentPolicy(in, settings)
+	if err != nil {
+		logger.Warnf(i18n.Translate(ctx, "order %s rejected: %v"), in.OrderID, err)
+		return models.Order{}, models.OrderOutcomeRejected, err
+	}
+
+	order := newOrder(in, holdReason, fulfilment)
+	if err := p.applyTax(ctx, &order); err != nil {
+		return models.Order{}, models.OrderOutcomeRejected, err
+	}
+
+	customer, err := p.linkCustomer(ctx, in)
+	if err != nil {
+		return models.Order{}, models.OrderOutcomeRejected, err
+	}
+	order.HubAllocation = hubAllocation
+	order.Customer = customer
+	return p.save(ctx, order, policy, opts.JobID)
+}
+
+// processSplit saves an order fulfilled from several hubs as a parent holding
+// every line and one child order per hub holding that hub's lines. Children
+// are validated, held and emitted like any other order; the parent is only
+// saved and takes its status from its children.
+func (p *orderPipeline) processSplit(ctx context.Context, in models.OrderInput, plan *allocation.SplitPlan, settings *models.TenantSettings, policy models.ConflictPolicy, opts processOptions) (models.Order, models.OrderOutcome, error) {
+	logger := log.DefaultLogger()
+
+	fulfilment, err := fulfilmentPolicy(in, settings)
+	if err != nil {
+		logger.Warnf(i18n.Translate(ctx, "order %s rejected: %v"), in.OrderID, err)
+		return models.Order{}, models.OrderOutcomeRejected, err
+	}
+
+	now := time.Now().UTC()
+	lines := in.NormalizedLines()
+	parent := newOrder(in, "", fulfilment)
+	parent.HubID = ""
+
+	children := make([]models.Order, 0, len(plan.Groups))
+	reasons := make([]string, 0, len(plan.Groups))
+	for n, group := range plan.Groups {
+		childIn := in
+		childIn.OrderID = fmt.Sprintf("%s%s%d", in.OrderID, childOrderIDSeparator, n+1)
+		childIn.HubID = group.HubID
+		// The parent carries the shipping charge.
+		childIn.Shipping = 0
+		childIn.Lines = make([]models.OrderLineInput, 0, len(group.Lines))
+		for _, j := range group.Lines {
+			childIn.Lines = append(childIn.Lines, lines[j])
+		}
+
+		holdReason, err := p.validate(ctx, childIn)
+		if err != nil {
+			logger.Warnf(i18n.Translate(ctx, "order %s rejected: %v"), in.OrderID, err)
+			return models.Order{}, models.OrderOutcomeRejected, err
+		}
+
+		child := newOrder(childIn, holdReason, fulfilment)
+		for k, j := range group.Lines {
+			child.Lines[k].LineNumber = j + 1
+		}
+		child.ParentOrderID = in.OrderID
+		child.HubAllocation = &models.HubAllocation{HubID: group.HubID, Strategy: models.AllocationSplit, Reason: group.Reason, Candidates: plan.Candidates, AllocatedAt: now}
+
+		children = append(children, child)
+		parent.ChildOrderIDs = append(parent.ChildOrderIDs, child.OrderID)
+		reasons = append(reasons, group.Reason)
+	}
+	parent.HubAllocation = &models.HubAllocation{Strategy: models.AllocationSplit, Reason: strings.Join(reasons, "; "), Candidates: plan.Candidates, AllocatedAt: now}
+
+	// Tax is worked out once for the whole order and shared out to the
+	// children by line number.
+	if err := p.applyTax(ctx, &parent); err != nil {
+		return models.Order{}, models.OrderOutcomeRejected, err
+	}
+	for i := range children {
+		for k := range children[i].Lines {
+			children[i].Lines[k].Tax = parent.Lines[children[i].Lines[k].LineNumber-1].Tax
+		}
+		children[i].ComputeTotals(0)
+	}
+
+	customer, err := p.linkCustomer(ctx, in)
+	if err != nil {
+		return models.Order{}, models.OrderOutcomeRejected, err
+	}
+	parent.Customer = customer
+	for i := range children {
+		children[i].Customer = customer
+	}
+
+	parent, outcome, err := p.persist(ctx, parent, policy)
+	if err != nil || outcome == models.OrderOutcomeSkipped {
+		return parent, outcome, err
+	}
+
+	// The split is saved whole or not at all, and children are only emitted
+	// once every one of them is saved. Only orders this call created are
+	// discarded; held orders it replaced were already there.
+	created := make([]models.Order, 0, len(children)+1)
+	if outcome == models.OrderOutcomeCreated {
+		created = append(created, parent)
+	}
+	for _, child := range children {
+		_, childOutcome, err := p.persist(ctx, child, policy)
+		if err == nil && childOutcome == models.OrderOutcomeSkipped {
+			err = errDuplicateOrder
+		}
+		if err != nil {
+			logger.Errorf(i18n.Translate(ctx, "failed to save child order %s of %s: %v"), child.OrderID, in.OrderID, err)
+			p.discard(ctx, created)
+			return models.Order{}, models.OrderOutcomeRejected, err
+		}
+		if childOutcome == models.OrderOutcomeCreated {
+			created = append(created, child)
+		}
+	}
+	logger.Infof(i18n.Translate(ctx, "order %s split across %d hubs"), in.OrderID, len(children))
+
+	for _, child := range children {
+		p.announce(ctx, child, opts.JobID)
+	}
+	return parent, outcome, nil
+}
+
+// discard deletes the orders created for a split that could not be saved
+// whole, so the order can be submitted again.
+func (p *orderPipeline) discard(ctx context.Context, orders []models.Order) {
+	for _, order := range orders {
+		if err := p.OrderService.DeleteOrder(ctx, order.CustomerID, order.OrderID); err != nil {
+			log.DefaultLogger().Errorf(i18n.Translate(ctx, "failed to delete order %s of an incomplete split: %v"), order.OrderID, err)
+		}
+	}
+}
+
+// newOrder builds an on_hold order from validated input, numbering its lines.
+func newOrder(in models.OrderInput, holdReason string, fulfilment models.FulfilmentPolicy) models.Order {
+	order := models.Order{OrderID: in.OrderID, CustomerName: in.CustomerName, HubID: in.HubID, Status: models.OrderStatusOnHold, CustomerID: in.TenantID, TenantID: in.TenantID, HoldReason: holdReason, FulfilmentPolicy: fulfilment, ShippingCoordinates: in.ShippingCoordinates}
+	order.ShippingAddress, order.BillingAddress = in.ShippingAddress, in.BillingAddress
+	order.CustomerEmail, order.CustomerPhone = in.CustomerEmail, in.CustomerPhone
+	order.Currency = in.Currency
+	if in.OrderDate != nil {
+		orderDate := in.OrderDate.UTC()
+		order.OrderDate = &orderDate
+	}
+	if in.PromiseDate != nil {
+		promiseDate := in.PromiseDate.UTC()
+		order.PromiseDate = &promiseDate
+	}
+	// Amounts were checked by checkPricing.
+	minor := func(amount float64) models.Money {
+		m, _ := models.ToMinor(amount, in.Currency)
+		return m
+	}
+	for i, line := range in.NormalizedLines() {
+		order.Lines = append(order.Lines, models.OrderLine{LineNumber: i + 1, SKUID: line.SKUID, Qty: line.Qty, Price: line.Price,
+			UnitPrice: minor(line.Price), Discount: minor(line.Discount), Tax: minor(line.Tax), TaxCategory: line.TaxCategory, Status: models.LineStatusPending})
+	}
+	order.ComputeTotals(minor(in.Shipping))
+	if len(order.Lines) == 1 {
+		order.SKUID, order.Qty, order.Price = order.Lines[0].SKUID, order.Lines[0].Qty, order.Lines[0].Price
+	}
+	return order
+}
+
+// save persists an order under policy and emits order.created unless it is
+// held or split.
+func (p *orderPipeline) save(ctx context.Context, order models.Order, policy models.ConflictPolicy, jobID string) (models.Order, models.OrderOutcome, error) {
+	order, outcome, err := p.persist(ctx, order, policy)
+	if err != nil || outcome == models.OrderOutcomeSkipped {
+		return order, outcome, err
+	}
+	p.announce(ctx, order, jobID)
+	return order, outcome, nil
+}
+
+// persist saves an order under policy without emitting it. Duplicates are
+// returned with errDuplicateOrder; skipped orders without an error.
+func (p *orderPipeline) persist(ctx context.Context, order models.Order, policy models.ConflictPolicy) (models.Order, models.OrderOutcome, error) {
+	logger := log.DefaultLogger()
+
+	outcome, err := p.OrderService.SaveOrder(ctx, order, policy)
+	if err != nil {
+		logger.Errorf(i18n.Translate(ctx, "failed to save order: %v"), err)
+		return models.Order{}, models.OrderOutcomeRejected, fmt.Errorf("%w: %v", errOrderNotSaved, err)
+	}
+
+	switch outcome {
+	case models.OrderOutcomeDuplicate:
+		logger.Warnf(i18n.Translate(ctx, "order %s rejected as duplicate"), order.OrderID)
+		return order, outcome, errDuplicateOrder
+	case models.OrderOutcomeSkipped:
+		logger.Infof(i18n.Translate(ctx, "order %s already exists, skipped under policy %s"), order.OrderID, policy)
+		return order, outcome, nil
+	}
+	return order, outcome, nil
+}
+
+// announce emits order.created for a saved order unless it is held or a
+// split parent.
+func (p *orderPipeline) announce(ctx context.Context, order models.Order, jobID string) {
+	if order.HoldReason != "" {
+		log.DefaultLogger().Infof(i18n.Translate(ctx, "order %s held: %s"), order.OrderID, order.HoldReason)
+		return
+	}
+	if len(order.ChildOrderIDs) > 0 {
+		return
+	}
+	p.emitOrderCreated(ctx, order, jobID)
+}
+
+func (p *orderPipeline) emitOrderCreated(ctx context.Context, order models.Order, jobID string) {
+	logger := log.DefaultLogger()
+
+	if jobID != "" {
+		claimed, err := p.EventLogService.ClaimEmission(ctx, order.CustomerID, order.OrderID, jobID, orderCreatedEvent)
+		if err != nil {
+			logger.Errorf(i18n.Translate(ctx, "failed to record emission for order_id %s: %v"), order.OrderID, err)
+			return
+		}
+		if !claimed {
+			logger.Infof(i18n.Translate(ctx, "order.created already emitted for order_id %s in job %s, skipping"), order.OrderID, jobID)
+			return
+		}
+	}
+
+	event := models.OrderCreatedEvent{OrderID: order.OrderID, SKUID: order.SKUID, HubID: order.HubID, Qty: order.Qty, Price: order.Price, CustomerID: order.CustomerID, ParentOrderID: order.ParentOrderID,
+		ShippingAddress: order.ShippingAddress, BillingAddress: order.BillingAddress, CustomerEmail: order.CustomerEmail, CustomerPhone: order.CustomerPhone,
+		Currency: order.Currency, Totals: order.Totals}
+	for _, line := range order.Lines {
+		event.Lines = append(event.Lines, models.OrderLineInput{SKUID: line.SKUID, Qty: line.Qty, Price: line.Price})
+	}
+
+	logger.Infof(i18n.Translate(ctx, "emitting Kafka event: %+v"), event)
+	if err := p.KafkaProducer.Emit(ctx, orderCreatedEvent, event); err != nil {
+		logger.Errorf(i18n.Translate(ctx, "failed to emit Kafka event for order_id %s: %v"), event.OrderID, err)
+		if jobID != "" {
+			if rerr := p.EventLogService.ReleaseEmission(ctx, order.CustomerID, order.OrderID, jobID, orderCreatedEvent); rerr != nil {
+				logger.Errorf(i18n.Translate(ctx, "failed to release emission for order_id %s: %v"), order.OrderID, rerr)
+			}
+		}
+		return
+	}
+	logger.Infof(i18n.Translate(ctx, "Kafka event emitted for order_id: %s"), event.OrderID)
+}
+
+// validate checks an order against the business rules and IMS. A non-empty
+// hold reason means the order is acceptable but cannot be fulfilled yet.
+func (p *orderPipeline) validate(ctx context.Context, in models.OrderInput) (string, error) {
+	if err := checkOrderData(in); err != nil {
+		return "", err
+	}
+	if in.HubID == "" {
+		return "", errMissingHubOrSKU
+	}
+	lines := in.NormalizedLines()
+
+	hub := p.Validator.ValidateHub(ctx, in.HubID)
+	if hub.IsTransient() {
+		return "", fmt.Errorf("%w: %w", errIMSUnavailable, hub.Err)
+	}
+	if hub.IsAuthError() {
+		return "", fmt.Errorf("%w: %w", errIMSUnauthorized, hub.Err)
+	}
+	if !hub.IsValid() {
+		return "", errInvalidHub
+	}
+
+	holdReason := ""
+	for _, line := range lines {
+		availability := p.Validator.ValidateSKUOnHub(ctx, in.HubID, line.SKUID)
+		if availability.IsTransient() {
+			return "", fmt.Errorf("%w: %w", errIMSUnavailable, availability.Err)
+		}
+		if availability.IsAuthError() {
+			return "", fmt.Errorf("%w: %w", errIMSUnauthorized, availability.Err)
+		}
+		if !availability.IsValid() {
+			return "", errSKUNotOnHub
+		}
+
+		if !availability.CanFulfil(line.Qty) {
+			if config.GetString(ctx, "ims_validation.insufficient_stock_action") == insufficientStockReject {
+				return "", errInsufficientStock
+			}
+			holdReason = models.ReasonInsufficientStock
+		}
+	}
+
+	return holdReason, nil
+}
+
+// validateOrder validates a stored order, such as an amended one, like a
+// submitted order.
+func (p *orderPipeline) validateOrder(ctx context.Context, order models.Order) (string, error) {
+	in := models.OrderInput{TenantID: order.CustomerID, OrderID: order.OrderID, CustomerName: order.CustomerName, HubID: order.HubID, Currency: order.Currency}
+	normalizeCurrency(ctx, &in)
+	for _, line := range order.Lines {
+		in.Lines = append(in.Lines, models.OrderLineInput{SKUID: line.SKUID, Qty: line.Qty, Price: line.Price})
+	}
+	return p.validate(ctx, in)
+}
+
+// checkOrderData checks the fields of an order that need no IMS lookup.
+func checkOrderData(in models.OrderInput) error {
+	if in.OrderID == "" || in.TenantID <= 0 {
+		return errInvalidOrderData
+	}
+
+	lines := in.NormalizedLines()
+	for _, line := range lines {
+		if line.Qty <= 0 || line.Price < 0 {
+			return errInvalidOrderData
+		}
+	}
+	for _, line := range lines {
+		if line.SKUID == "" {
+			return errMissingHubOrSKU
+		}
+	}
+	if err := checkPricing(in); err != nil {
+		return err
+	}
+	if in.OrderDate != nil && in.PromiseDate != nil && in.PromiseDate.Before(in.OrderDate.Time) {
+		return errInvalidDates
+	}
+	if err := checkAddress("shipping_address", in.ShippingAddress); err != nil {
+		return err
+	}
+	if err := checkAddress("billing_address", in.BillingAddress); err != nil {
+		return err
+	}
+	if err := models.ValidateContact(in.CustomerEmail, in.CustomerPhone); err != nil {
+		return &orderError{Code: models.ReasonInvalidContact, Message: err.Error()}
+	}
+	return nil
+}
+
+// checkPricing checks the currency of an order, that its amounts are finite
+// and non-negative with no more decimals than the currency has, and that its
+// totals stay within the largest amount OMS stores.
+func checkPricing(in models.OrderInput) error {
+	if !models.ValidCurrency(in.Currency) {
+		return errInvalidCurrency
+	}
+	total, err := models.ToMinor(in.Shipping, in.Currency)
+	if err != nil {
+		return &orderError{Code: models.ReasonInvalidPricing, Message: "shipping: " + err.Error()}
+	}
+	for _, line := range in.NormalizedLines() {
+		price, err := models.ToMinor(line.Price, in.Currency)
+		if err != nil {
+			return &orderError{Code: models.ReasonInvalidPricing, Message: "price: " + err.Error()}
+		}
+		discount, err := models.ToMinor(line.Discount, in.Currency)
+		if err != nil {
+			return &orderError{Code: models.ReasonInvalidPricing, Message: "discount: " + err.Error()}
+		}
+		tax, err := models.ToMinor(line.Tax, in.Currency)
+		if err != nil {
+			return &orderError{Code: models.ReasonInvalidPricing, Message: "tax: " + err.Error()}
+		}
+		gross, err := models.LineGross(line.Qty, price)
+		if err != nil {
+			return &orderError{Code: models.ReasonInvalidPricing, Message: "quantity times price: " + err.Error()}
+		}
+		if discount > gross {
+			return errDiscountTooLarge
+		}
+		if total, err = models.SumMoney(total, gross, tax); err != nil {
+			return &orderError{Code: models.ReasonInvalidPricing, Message: "order total: " + err.Error()}
+		}
+	}
+	return nil
+}
+
+func checkAddress(name string, address *models.Address) error {
+	if address == nil {
+		return nil
+	}
+	if err := address.Validate(); err != nil {
+		return &orderError{Code: models.ReasonInvalidAddress, Message: name + ": " + err.Error()}
+	}
+	return nil
+}
+
+// normalizeContact tidies the addresses, email, phone and customer ID of an
+// order before they are validated and stored. Empty addresses are dropped.
+func normalizeContact(in *models.OrderInput) {
+	normalize := func(address *models.Address) *models.Address {
+		if address == nil {
+			return nil
+		}
+		a := *address
+		a.Normalize()
+		if a.IsEmpty() {
+			return nil
+		}
+		return &a
+	}
+	in.ShippingAddress = normalize(in.ShippingAddress)
+	in.BillingAddress = normalize(in.BillingAddress)
+	in.CustomerEmail = strings.TrimSpace(in.CustomerEmail)
+	in.CustomerPhone = models.NormalizePhone(strings.TrimSpace(in.CustomerPhone))
+	in.CustomerExternalID = strings.TrimSpace(in.CustomerExternalID)
+}
+
+// applyTax works out the tax of a new order with its tenant's calculator.
+func (p *orderPipeline) applyTax(ctx context.Context, order *models.Order) error {
+	if err := p.TaxService.Apply(ctx, order); err != nil {
+		log.DefaultLogger().Errorf(i18n.Translate(ctx, "failed to calculate tax of order %s: %v"), order.OrderID, err)
+		return errTaxUnavailable
+	}
+	return nil
+}
+
+// linkCustomer upserts the customer of an order with a customer_external_id
+// and returns the link to store on the order. Orders without one are not
+// linked.
+func (p *orderPipeline) linkCustomer(ctx context.Context, in models.OrderInput) (*models.OrderCustomer, error) {
+	if in.CustomerExternalID == "" {
+		return nil, nil
+	}
+
+	customer, err := p.CustomerService.Upsert(ctx, models.Customer{
+		TenantID:       in.TenantID,
+		ExternalID:     in.CustomerExternalID,
+		Name:           in.CustomerName,
+		Email:          in.CustomerEmail,
+		Phone:          in.CustomerPhone,
+		DefaultAddress: in.ShippingAddress,
+	})
+	if err != nil {
+		log.DefaultLogger().Errorf(i18n.Translate(ctx, "failed to save customer %s of order %s: %v"), in.CustomerExternalID, in.OrderID, err)
+		return nil, fmt.Errorf("%w: %v", errOrderNotSaved, err)
+	}
+	return &models.OrderCustomer{ID: customer.CustomerID, ExternalID: customer.ExternalID}, nil
+}
+
+// normalizeCurrency upper-cases the currency of an order, defaulting it to
+// orders.default_currency.
+func normalizeCurrency(ctx context.Context, in *models.OrderInput) {
+	in.Currency = strings.ToUpper(strings.TrimSpace(in.Currency))
+	if in.Currency == "" {
+		in.Currency = config.GetString(ctx, "orders.default_currency")
+	}
+}
+
+// allocateHub asks the allocation engine for a hub, honouring the tenant's
+// hub priority and strategy order.
+func (p *orderPipeline) allocateHub(ctx context.Context, in models.OrderInput, settings *models.TenantSettings) (*models.HubAllocation, error) {
+	var strategies []models.AllocationStrategy
+	if settings != nil {
+		strategies = settings.AllocationStrategies
+	}
+
+	decision, err := p.Allocator.Allocate(ctx, allocationRequest(in, settings), strategies)
+	if err != nil {
+		return nil, allocationError(err)
+	}
+	return decision, nil
+}
+
+func allocationRequest(in models.OrderInput, settings *models.TenantSettings) allocation.Request {
+	req := allocation.Request{TenantID: in.TenantID, Lines: in.NormalizedLines(), ShippingCoordinates: in.ShippingCoordinates}
+	if settings != nil {
+		req.HubPriority = settings.HubPriority
+	}
+	return req
+}
+
+// allocationError maps an allocation engine error onto a pipeline error.
+func allocationError(err error) error {
+	if errors.Is(err, allocation.ErrNoHubAvailable) {
+		return errNoHubAvailable
+	}
+	if IMS_APIS.IsAuthError(err) {
+		return fmt.Errorf("%w: %w", errIMSUnauthorized, err)
+	}
+	return fmt.Errorf("%w: %w", errIMSUnavailable, err)
+}
+
+// splitAllowed reports whether an order may be split across hubs. The
+// order's allow_split overrides the tenant's split_orders setting.
+func splitAllowed(in models.OrderInput, settings *models.TenantSettings) bool {
+	if in.AllowSplit != nil {
+		return *in.AllowSplit
+	}
+	return settings != nil && settings.SplitOrders
+}
+
+// tenantSettings loads a tenant's settings. Lookup failures are logged and
+// treated as no settings so orders fall back to the defaults.
+func (p *orderPipeline) tenantSettings(ctx context.Context, tenantID int) *models.TenantSettings {
+	if tenantID <= 0 {
+		return nil
+	}
+	settings, err := p.TenantSettingsService.Get(ctx, tenantID)
+	if err != nil {
+		log.DefaultLogger().Errorf(i18n.Translate(ctx, "failed to load settings for tenant %d: %v"), tenantID, err)
+	}
+	return settings
+}
+
+// fulfilmentPolicy resolves the order's fulfilment policy from the order,
+// then the tenant's settings, then the default.
+func fulfilmentPolicy(in models.OrderInput, settings *models.TenantSettings) (models.FulfilmentPolicy, error) {
+	if in.FulfilmentPolicy != "" {
+		if !in.FulfilmentPolicy.IsValid() {
+			return "", errInvalidFulfilment
+		}
+		return in.FulfilmentPolicy, nil
+	}
+
+	if settings != nil && settings.FulfilmentPolicy.IsValid() {
+		return settings.FulfilmentPolicy, nil
+	}
+	return models.DefaultFulfilmentPolicy, nil
+}
+
+// orderInputFromRow maps a CSV or spreadsheet row onto an OrderInput using the
+// file's header positions.
+func orderInputFromRow(row []string, colIdx map[string]int) (models.OrderInput, error) {
+	field := func(name string) string {
+		i, ok := colIdx[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	// Identifiers and policies are matched case-insensitively in files;
+	// names, contact details and addresses keep their case as on the API.
+	lowerField := func(name string) string {
+		return strings.ToLower(field(name))
+	}
+
+	in := models.OrderInput{
+		OrderID:          lowerField("order_id"),
+		CustomerName:     field("customer_name"),
+		HubID:            lowerField("hub_id"),
+		SKUID:            lowerField("sku_id"),
+		FulfilmentPolicy: models.FulfilmentPolicy(lowerField("fulfilment_policy")),
+	}
+
+	qty, err := strconv.Atoi(field("quantity"))
+	price, perr := strconv.ParseFloat(field("price"), 64)
+	tenantID, cerr := strconv.Atoi(field("tenant_id"))
+	if err != nil || perr != nil || cerr != nil {
+		return in, errInvalidOrderData
+	}
+
+	in.Qty = qty
+	in.Price = price
+	in.TenantID = tenantID
+
+	if lat, lng := field("shipping_latitude"), field("shipping_longitude"); lat != "" || lng != "" {
+		latitude, err := strconv.ParseFloat(lat, 64)
+		longitude, lerr := strconv.ParseFloat(lng, 64)
+		if err != nil || lerr != nil {
+			return in, errInvalidOrderData
+		}
+		in.ShippingCoordinates = &models.Coordinates{Latitude: latitude, Longitude: longitude}
+	}
+
+	in.ShippingAddress = addressFromRow(field, "shipping_")
+	in.BillingAddress = addressFromRow(field, "billing_")
+	in.CustomerEmail = field("customer_email")
+	in.CustomerPhone = field("customer_phone")
+	in.CustomerExternalID = field("customer_external_id")
+	in.Currency = field("currency")
+	in.TaxCategory = field("tax_category")
+
+	discount, derr := parseAmount(field("discount"))
+	tax, terr := parseAmount(field("tax"))
+	shipping, serr := parseAmount(field("shipping"))
+	if derr != nil || terr != nil || serr != nil {
+		return in, errInvalidAmount
+	}
+	in.Discount, in.Tax, in.Shipping = discount, tax, shipping
+
+	if value := field("order_date"); value != "" {
+		orderDate, err := models.ParseDate(value)
+		if err != nil {
+			return in, errInvalidDates
+		}
+		in.OrderDate = &models.Date{Time: orderDate}
+	}
+	if value := field("promise_date"); value != "" {
+		promiseDate, err := models.ParseDate(value)
+		if err != nil {
+			return in, errInvalidDates
+		}
+		in.PromiseDate = &models.Date{Time: promiseDate}
+	}
+	return in, nil
+}
+
+// addressFromRow reads an address from the columns starting with prefix,
+// such as shipping_line1. It returns nil when they are all empty.
+func addressFromRow(field func(string) string, prefix string) *models.Address {
+	address := models.Address{
+		Name:        field(prefix + "name"),
+		Line1:       field(prefix + "line1"),
+		Line2:       field(prefix + "line2"),
+		City:        field(prefix + "city"),
+		State:       field(prefix + "state"),
+		PostalCode:  field(prefix + "postal_code"),
+		CountryCode: field(prefix + "country_code"),
+	}
+	if address.IsEmpty() {
+		return nil
+	}
+	return &address
+}
+
+// parseAmount parses an optional amount column, treating an empty one as 0.
+func parseAmount(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
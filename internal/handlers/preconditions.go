@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/RohitGupta-omniful/OMS/services"
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+)
+
+const ifMatchHeader = "If-Match"
+
+// expectedVersion reads the order version a request was made against from
+// its If-Match header. Requests without one, or with "*", get AnyVersion.
+// ok is false, and a 400 has been sent, when the header is not an ETag
+// returned by OMS.
+func expectedVersion(c *gin.Context) (version int, ok bool) {
+	value := strings.TrimSpace(c.GetHeader(ifMatchHeader))
+	if value == "" || value == "*" {
+		return services.AnyVersion, true
+	}
+
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "If-Match must be a single order ETag")})
+		return 0, false
+	}
+	return version, true
+}
+
+// setETag exposes the order's version as its ETag.
+func setETag(c *gin.Context, order *models.Order) {
+	if order != nil {
+		c.Header("ETag", strconv.Quote(strconv.Itoa(order.Version)))
+	}
+}
+
+// respondVersionConflict answers a write that lost to another change of the
+// order: 412 when the client sent If-Match, 409 otherwise.
+func respondVersionConflict(c *gin.Context, err error) {
+	status := http.StatusConflict
+	if c.GetHeader(ifMatchHeader) != "" {
+		status = http.StatusPreconditionFailed
+	}
+	c.JSON(status, gin.H{"error": i18n.Translate(c, services.ErrVersionConflict.Error())})
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/RohitGupta-omniful/OMS/services"
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+type ReturnNoteRequest struct {
+	Notes string `json:"notes"`
+}
+
+type ReturnRefundRequest struct {
+	Amount *float64 `json:"amount,omitempty"`
+}
+
+type ReturnRejectRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CreateReturn requests a return of delivered order lines.
+func (h *Handler) CreateReturn(c *gin.Context) {
+	var in models.ReturnInput
+	if err := c.BindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid request body")})
+		return
+	}
+
+	rma, err := h.ReturnService.Create(c.Request.Context(), c.Param("order_id"), in)
+	h.respondReturn(c, http.StatusCreated, rma, err)
+}
+
+// ListReturns returns the returns of an order.
+func (h *Handler) ListReturns(c *gin.Context) {
+	returns, err := h.ReturnService.List(c.Request.Context(), c.Param("order_id"))
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "order not found")})
+	case err != nil:
+		log.Errorf(i18n.Translate(c, "failed to list returns: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to list returns")})
+	default:
+		c.JSON(http.StatusOK, gin.H{"returns": returns})
+	}
+}
+
+// GetReturn returns a single return.
+func (h *Handler) GetReturn(c *gin.Context) {
+	rma, err := h.ReturnService.Get(c.Request.Context(), c.Param("rma_id"))
+	h.respondReturn(c, http.StatusOK, rma, err)
+}
+
+// ApproveReturn authorizes a requested return.
+func (h *Handler) ApproveReturn(c *gin.Context) {
+	rma, err := h.ReturnService.Approve(c.Request.Context(), c.Param("rma_id"))
+	h.respondReturn(c, http.StatusOK, rma, err)
+}
+
+// ReceiveReturn records returned items by condition and restocks the
+// sellable ones.
+func (h *Handler) ReceiveReturn(c *gin.Context) {
+	var in models.ReturnReceiptInput
+	if err := c.BindJSON(&in); err != nil || len(in.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid request body")})
+		return
+	}
+
+	rma, err := h.ReturnService.Receive(c.Request.Context(), c.Param("rma_id"), in)
+	h.respondReturn(c, http.StatusOK, rma, err)
+}
+
+// InspectReturn records the inspection of received items.
+func (h *Handler) InspectReturn(c *gin.Context) {
+	var req ReturnNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid request body")})
+		return
+	}
+
+	rma, err := h.ReturnService.Inspect(c.Request.Context(), c.Param("rma_id"), req.Notes)
+	h.respondReturn(c, http.StatusOK, rma, err)
+}
+
+// RefundReturn closes an inspected return with a refund.
+func (h *Handler) RefundReturn(c *gin.Context) {
+	var req ReturnRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid request body")})
+		return
+	}
+
+	rma, err := h.ReturnService.Refund(c.Request.Context(), c.Param("rma_id"), req.Amount)
+	h.respondReturn(c, http.StatusOK, rma, err)
+}
+
+// RejectReturn closes a return without a refund.
+func (h *Handler) RejectReturn(c *gin.Context) {
+	var req ReturnRejectRequest
+	if err := c.BindJSON(&req); err != nil || req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "reason is required")})
+		return
+	}
+
+	rma, err := h.ReturnService.Reject(c.Request.Context(), c.Param("rma_id"), req.Reason)
+	h.respondReturn(c, http.StatusOK, rma, err)
+}
+
+func (h *Handler) respondReturn(c *gin.Context, status int, rma *models.ReturnAuthorization, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "order not found")})
+	case errors.Is(err, services.ErrReturnNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "return not found")})
+	case errors.Is(err, services.ErrInvalidReturn), errors.Is(err, services.ErrInvalidReceipt), errors.Is(err, services.ErrInvalidRefund):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, err.Error())})
+	case errors.Is(err, services.ErrInvalidReturnTransition), errors.Is(err, services.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, err.Error())})
+	case err != nil && rma != nil:
+		// The return was received but restocking failed; it is retried in
+		// the background.
+		c.JSON(http.StatusAccepted, gin.H{"return": rma, "warning": i18n.Translate(c, "restock pending")})
+	case err != nil:
+		log.Errorf(i18n.Translate(c, "failed to update return: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to update return")})
+	default:
+		c.JSON(status, rma)
+	}
+}
@@ -3,22 +3,49 @@ package handlers
 import (
 	"context"
 
+	"github.com/RohitGupta-omniful/OMS/IMS_APIS"
+	"github.com/RohitGupta-omniful/OMS/internal/allocation"
+	"github.com/RohitGupta-omniful/OMS/internal/breaker"
+	"github.com/RohitGupta-omniful/OMS/kafka"
+	"github.com/RohitGupta-omniful/OMS/services"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/omniful/go_commons/i18n"
 	"github.com/omniful/go_commons/log"
 )
 
 type Handler struct {
-	S3Client *s3.Client
+	S3Client              *s3.Client
+	OrderService          *services.OrderService
+	IdempotencyService    *services.IdempotencyService
+	BulkJobService        *services.BulkJobService
+	ReservationService    *services.ReservationService
+	TenantSettingsService *services.TenantSettingsService
+	ShipmentService       *services.ShipmentService
+	ReturnService         *services.ReturnService
+	AmendmentService      *services.AmendmentService
+	CustomerService       *services.CustomerService
+	Breakers              []*breaker.Breaker
+	pipeline              *orderPipeline
 }
 
-func NewHandler(ctx context.Context, s3Client *s3.Client) *Handler {
+func NewHandler(ctx context.Context, s3Client *s3.Client, orderService *services.OrderService, kafkaProducer *kafka.Producer, validator *IMS_APIS.Validator, allocator *allocation.Engine, taxService *services.TaxService, reservationService *services.ReservationService, shipmentService *services.ShipmentService, returnService *services.ReturnService, amendmentService *services.AmendmentService, breakers []*breaker.Breaker) *Handler {
 	if s3Client == nil {
 		log.Warnf(i18n.Translate(ctx, "S3 client is not set up"))
 	} else {
 		log.Infof(i18n.Translate(ctx, "S3 client successfully set up"))
 	}
 	return &Handler{
-		S3Client: s3Client,
+		S3Client:              s3Client,
+		OrderService:          orderService,
+		IdempotencyService:    services.NewIdempotencyService(),
+		BulkJobService:        services.NewBulkJobService(),
+		ReservationService:    reservationService,
+		TenantSettingsService: services.NewTenantSettingsService(),
+		ShipmentService:       shipmentService,
+		ReturnService:         returnService,
+		AmendmentService:      amendmentService,
+		CustomerService:       services.NewCustomerService(),
+		Breakers:              breakers,
+		pipeline:              newOrderPipeline(orderService, kafkaProducer, validator, allocator, taxService),
 	}
 }
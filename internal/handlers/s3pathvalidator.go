@@ -3,31 +3,54 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/RohitGupta-omniful/OMS/IMS_APIS"
 	"github.com/RohitGupta-omniful/OMS/internal/SQS"
+	"github.com/RohitGupta-omniful/OMS/internal/allocation"
+	"github.com/RohitGupta-omniful/OMS/internal/breaker"
 	"github.com/RohitGupta-omniful/OMS/kafka"
 	"github.com/RohitGupta-omniful/OMS/models"
 	"github.com/RohitGupta-omniful/OMS/services"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
 	"github.com/omniful/go_commons/config"
-	"github.com/omniful/go_commons/csv"
 	"github.com/omniful/go_commons/i18n"
 	"github.com/omniful/go_commons/log"
 	"github.com/omniful/go_commons/sqs"
 )
 
 type UploadRequest struct {
-	S3Path string `json:"s3_path"`
+	S3Path    string `json:"s3_path"`
+	SheetName string `json:"sheet_name,omitempty"`
+	// TenantID is optional; the tenant comes from the authenticated request
+	// and a different value here is rejected.
+	TenantID int  `json:"tenant_id,omitempty"`
+	Force    bool `json:"force,omitempty"`
+	// ConflictPolicy is one of skip_existing, update_on_hold (default) or
+	// reject_duplicate.
+	ConflictPolicy models.ConflictPolicy `json:"conflict_policy,omitempty"`
+}
+
+const defaultIMSRetryBackoff = 2 * time.Second
+
+// bulkOrderMessage is the payload published to CreateBulkOrderQueue.
+// TenantID is the tenant that uploaded the file; rows for any other tenant
+// are rejected.
+type bulkOrderMessage struct {
+	JobID          string                `json:"job_id,omitempty"`
+	TenantID       int                   `json:"tenant_id,omitempty"`
+	Bucket         string                `json:"bucket"`
+	Key            string                `json:"key"`
+	Sheet          string                `json:"sheet,omitempty"`
+	ConflictPolicy models.ConflictPolicy `json:"conflict_policy,omitempty"`
 }
 
 type BulkOrderRequest struct {
@@ -48,9 +71,23 @@ func (h *Handler) UploadCSV(c *gin.Context) {
 		return
 	}
 
+	tenantID, ok := claimedTenant(c, req.TenantID)
+	if !ok {
+		return
+	}
+	req.TenantID = tenantID
+
+	if req.ConflictPolicy == "" {
+		req.ConflictPolicy = models.DefaultConflictPolicy
+	}
+	if !req.ConflictPolicy.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid conflict_policy")})
+		return
+	}
+
 	bucket, key := parseS3Path(req.S3Path)
 
-	_, err := h.S3Client.HeadObject(c.Request.Context(), &s3.HeadObjectInput{
+	head, err := h.S3Client.HeadObject(c.Request.Context(), &s3.HeadObjectInput{
 		Bucket: &bucket,
 		Key:    &key,
 	})
@@ -60,21 +97,70 @@ func (h *Handler) UploadCSV(c *gin.Context) {
 		return
 	}
 
+	checksum := objectChecksum(head)
+
+	if !req.Force && checksum != "" {
+		existing, err := h.BulkJobService.FindByChecksum(c.Request.Context(), req.TenantID, checksum)
+		if err != nil {
+			log.Errorf(i18n.Translate(c, "failed to look up bulk job by checksum: %v"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to create bulk job")})
+			return
+		}
+		if existing != nil {
+			respondDuplicateUpload(c, bucket, key, existing)
+			return
+		}
+	}
+
+	job := &models.BulkJob{
+		TenantID:       req.TenantID,
+		Bucket:         bucket,
+		Key:            key,
+		Sheet:          req.SheetName,
+		Checksum:       checksum,
+		Forced:         req.Force,
+		ConflictPolicy: req.ConflictPolicy,
+	}
+	err = h.BulkJobService.CreateJob(c.Request.Context(), job)
+	if errors.Is(err, services.ErrDuplicateJob) {
+		// A concurrent upload of the same file created its job first.
+		existing, ferr := h.BulkJobService.FindByChecksum(c.Request.Context(), req.TenantID, checksum)
+		if ferr != nil {
+			err = ferr
+		} else if existing != nil {
+			respondDuplicateUpload(c, bucket, key, existing)
+			return
+		}
+	}
+	if err != nil {
+		log.Errorf(i18n.Translate(c, "failed to create bulk job: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to create bulk job")})
+		return
+	}
+
 	publisher, err := SQS.PublishCreateBulkOrderEvent(c.Request.Context())
 	if err != nil {
 		log.Errorf(i18n.Translate(c, "failed to publish event to SQS: %v"), err)
+		h.failJob(c, job.JobID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to push event to queue")})
 		return
 	}
 
-	payload := `{"bucket":"` + bucket + `", "key":"` + key + `"}`
+	payload, err := json.Marshal(bulkOrderMessage{JobID: job.JobID, TenantID: req.TenantID, Bucket: bucket, Key: key, Sheet: req.SheetName, ConflictPolicy: req.ConflictPolicy})
+	if err != nil {
+		log.Errorf(i18n.Translate(c, "failed to marshal queue payload: %v"), err)
+		h.failJob(c, job.JobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to push event to queue")})
+		return
+	}
 
 	msg := &sqs.Message{
-		Value: []byte(payload),
+		Value: payload,
 	}
 
 	if err = publisher.Publish(c.Request.Context(), msg); err != nil {
 		log.Errorf(i18n.Translate(c, "failed to publish message to queue: %v"), err)
+		h.failJob(c, job.JobID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to publish message to queue")})
 		return
 	}
@@ -82,19 +168,97 @@ func (h *Handler) UploadCSV(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message":      i18n.Translate(c, "CSV validated and SQS event published successfully"),
 		"published_to": "CreateBulkOrderQueue",
-		"payload":      payload,
+		"payload":      string(payload),
+		"duplicate":    false,
+		"job":          job,
+	})
+}
+
+// GetBulkJobRows returns the per-row outcomes of a bulk upload job. An
+// optional outcome query parameter filters the rows.
+func (h *Handler) GetBulkJobRows(c *gin.Context) {
+	tenantID, ok := requestTenant(c)
+	if !ok {
+		return
+	}
+
+	jobID := c.Param("job_id")
+	if _, err := h.BulkJobService.GetTenantJob(c.Request.Context(), tenantID, jobID); err != nil {
+		if errors.Is(err, services.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "bulk job not found")})
+			return
+		}
+		log.Errorf(i18n.Translate(c, "failed to load bulk job: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to load bulk job")})
+		return
+	}
+
+	rows, err := h.BulkJobService.ListRows(c.Request.Context(), jobID, models.OrderOutcome(c.Query("outcome")))
+	if err != nil {
+		log.Errorf(i18n.Translate(c, "failed to load bulk job rows: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to load bulk job rows")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job_id": jobID, "rows": rows})
+}
+
+// GetBulkJob returns the status and counts of a bulk upload job.
+func (h *Handler) GetBulkJob(c *gin.Context) {
+	tenantID, ok := requestTenant(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.BulkJobService.GetTenantJob(c.Request.Context(), tenantID, c.Param("job_id"))
+	if errors.Is(err, services.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "bulk job not found")})
+		return
+	}
+	if err != nil {
+		log.Errorf(i18n.Translate(c, "failed to load bulk job: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to load bulk job")})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *Handler) failJob(ctx context.Context, jobID string, cause error) {
+	if err := h.BulkJobService.FailJob(ctx, jobID, cause.Error()); err != nil {
+		log.Errorf(i18n.Translate(ctx, "failed to mark bulk job %s as failed: %v"), jobID, err)
+	}
+}
+
+// respondDuplicateUpload answers an upload of a file the tenant already
+// submitted with the existing job.
+func respondDuplicateUpload(c *gin.Context, bucket, key string, existing *models.BulkJob) {
+	log.Infof(i18n.Translate(c, "file s3://%s/%s already submitted as job %s"), bucket, key, existing.JobID)
+	c.JSON(http.StatusOK, gin.H{
+		"message":   i18n.Translate(c, "file already submitted, returning existing job"),
+		"duplicate": true,
+		"job":       existing,
 	})
 }
 
+// objectChecksum identifies the content of an S3 object, preferring its
+// SHA-256 checksum and falling back to the ETag.
+func objectChecksum(head *s3.HeadObjectOutput) string {
+	if head.ChecksumSHA256 != nil && *head.ChecksumSHA256 != "" {
+		return "sha256:" + *head.ChecksumSHA256
+	}
+	if head.ETag != nil {
+		return "etag:" + strings.Trim(*head.ETag, `"`)
+	}
+	return ""
+}
+
 func parseS3Path(s3Path string) (bucket string, key string) {
 	trimmed := strings.TrimPrefix(s3Path, "s3://")
 	parts := strings.SplitN(trimmed, "/", 2)
 	return parts[0], parts[1]
 }
 
-func StartCSVProcessor(ctx context.Context, s3Client s3.Client, orderService *services.OrderService) {
+func StartCSVProcessor(ctx context.Context, s3Client s3.Client, orderService *services.OrderService, kafkaProducer *kafka.Producer, validator *IMS_APIS.Validator, allocator *allocation.Engine, taxService *services.TaxService, imsBreaker *breaker.Breaker) {
 	logger := log.DefaultLogger()
-	kafkaProducer := kafka.NewProducerWithConfig("order.created", []string{"localhost:9092"})
 
 	queueURL := config.GetString(ctx, "sqs.bulkOrderQueueUrl")
 	queueName := path.Base(queueURL)
@@ -116,10 +280,11 @@ func StartCSVProcessor(ctx context.Context, s3Client s3.Client, orderService *se
 		uint64(config.GetInt(ctx, "sqs.consumer.workerCount")),
 		uint64(1),
 		&queueHandler{
-			S3Client:      s3Client,
-			OrderService:  orderService,
-			SQSQueue:      qObj,
-			KafkaProducer: kafkaProducer,
+			S3Client:       s3Client,
+			SQSQueue:       qObj,
+			Pipeline:       newOrderPipeline(orderService, kafkaProducer, validator, allocator, taxService),
+			BulkJobService: services.NewBulkJobService(),
+			IMSBreaker:     imsBreaker,
 		},
 		int64(config.GetInt(ctx, "sqs.consumer.batchSize")),
 		int64(config.GetDuration(ctx, "sqs.consumer.visibilityTimeout").Seconds()),
@@ -134,182 +299,281 @@ func StartCSVProcessor(ctx context.Context, s3Client s3.Client, orderService *se
 }
 
 type queueHandler struct {
-	S3Client      s3.Client
-	OrderService  *services.OrderService
-	SQSQueue      *sqs.Queue
-	KafkaProducer *kafka.Producer
+	S3Client       s3.Client
+	SQSQueue       *sqs.Queue
+	Pipeline       *orderPipeline
+	BulkJobService *services.BulkJobService
+	// IMSBreaker guards IMS; while it is open messages are left on the queue.
+	IMSBreaker *breaker.Breaker
 }
 
 func (h *queueHandler) Process(ctx context.Context, msgs *[]sqs.Message) error {
 	logger := log.DefaultLogger()
+	var requeueErr error
 
-	for _, msg := range *msgs {
-		var evt struct {
-			Bucket string `json:"bucket"`
-			Key    string `json:"key"`
-		}
+	if h.IMSBreaker.IsOpen() {
+		logger.Warnf(i18n.Translate(ctx, "IMS circuit breaker is open, leaving %d messages on the queue"), len(*msgs))
+		return breaker.ErrOpen
+	}
 
+	for _, msg := range *msgs {
+		var evt bulkOrderMessage
 		if err := json.Unmarshal(msg.Value, &evt); err != nil {
 			logger.Errorf(i18n.Translate(ctx, "invalid SQS JSON: %v"), err)
 			continue
 		}
 
-		logger.Infof(i18n.Translate(ctx, "processing file: s3://%s/%s"), evt.Bucket, evt.Key)
-
-		getObjOutput, err := h.S3Client.GetObject(ctx, &s3.GetObjectInput{Bucket: &evt.Bucket, Key: &evt.Key})
-		if err != nil {
-			logger.Errorf(i18n.Translate(ctx, "failed to download CSV from S3: %v"), err)
-			continue
-		}
-		defer getObjOutput.Body.Close()
-
-		tmpFile := filepath.Join(os.TempDir(), filepath.Base(evt.Key))
-		outFile, err := os.Create(tmpFile)
-		if err != nil {
-			logger.Errorf(i18n.Translate(ctx, "failed to create temp file: %v"), err)
-			continue
+		resumeRow := 0
+		if evt.JobID != "" {
+			job, err := h.BulkJobService.GetJob(ctx, evt.JobID)
+			if err != nil {
+				logger.Errorf(i18n.Translate(ctx, "failed to load bulk job %s: %v"), evt.JobID, err)
+				continue
+			}
+			if job.Status == models.BulkJobStatusCompleted {
+				logger.Infof(i18n.Translate(ctx, "bulk job %s already completed, skipping redelivered message"), evt.JobID)
+				continue
+			}
+			resumeRow = job.ResumeRow
+			if evt.TenantID == 0 {
+				// Messages published before the tenant was carried.
+				evt.TenantID = job.TenantID
+			}
+			if err := h.BulkJobService.MarkProcessing(ctx, evt.JobID, resumeRow); err != nil {
+				logger.Errorf(i18n.Translate(ctx, "failed to mark bulk job %s as processing: %v"), evt.JobID, err)
+			}
 		}
-		defer outFile.Close()
 
-		if _, err = io.Copy(outFile, getObjOutput.Body); err != nil {
-			logger.Errorf(i18n.Translate(ctx, "failed to write S3 object to file: %v"), err)
+		result, err := h.processFile(ctx, evt, resumeRow)
+		var requeue *requeueError
+		if errors.As(err, &requeue) {
+			logger.Warnf(i18n.Translate(ctx, "requeueing file s3://%s/%s after row %d: %v"), evt.Bucket, evt.Key, requeue.ResumeRow, err)
+			if evt.JobID != "" {
+				if rerr := h.BulkJobService.RequeueJob(ctx, evt.JobID, requeue.ResumeRow, err.Error()); rerr != nil {
+					logger.Errorf(i18n.Translate(ctx, "failed to requeue bulk job %s: %v"), evt.JobID, rerr)
+				}
+			}
+			requeueErr = err
 			continue
 		}
-
-		csvReader, err := csv.NewCommonCSV(
-			csv.WithBatchSize(100),
-			csv.WithSource(csv.Local),
-			csv.WithLocalFileInfo(tmpFile),
-			csv.WithHeaderSanitizers(csv.SanitizeAsterisks, csv.SanitizeToLower),
-			csv.WithDataRowSanitizers(csv.SanitizeSpace, csv.SanitizeToLower),
-		)
 		if err != nil {
-			logger.Errorf(i18n.Translate(ctx, "failed to create CSV reader: %v"), err)
-			continue
-		}
-		if err = csvReader.InitializeReader(ctx); err != nil {
-			logger.Errorf(i18n.Translate(ctx, "failed to initialize CSV reader: %v"), err)
+			logger.Errorf(i18n.Translate(ctx, "failed to process file s3://%s/%s: %v"), evt.Bucket, evt.Key, err)
+			if evt.JobID != "" {
+				if ferr := h.BulkJobService.FailJob(ctx, evt.JobID, err.Error()); ferr != nil {
+					logger.Errorf(i18n.Translate(ctx, "failed to mark bulk job %s as failed: %v"), evt.JobID, ferr)
+				}
+			}
 			continue
 		}
 
-		headers, err := csvReader.GetHeaders()
-		if err != nil {
-			logger.Errorf(i18n.Translate(ctx, "failed to read CSV headers: %v"), err)
-			continue
+		if evt.JobID != "" {
+			if err := h.BulkJobService.CompleteJob(ctx, evt.JobID, result.Counts, result.ReportPath); err != nil {
+				logger.Errorf(i18n.Translate(ctx, "failed to complete bulk job %s: %v"), evt.JobID, err)
+			}
 		}
-		logger.Infof(i18n.Translate(ctx, "CSV headers: %v"), headers)
+	}
 
-		colIdx := make(map[string]int)
-		for i, col := range headers {
-			colIdx[col] = i
-		}
+	// Returning an error leaves the messages on the queue, so SQS redelivers
+	// requeued files once the visibility timeout expires.
+	return requeueErr
+}
 
-		var invalid csv.Records
+// requeueError stops a file when IMS stays unavailable. Rows up to ResumeRow
+// have been recorded and are skipped on the next attempt.
+type requeueError struct {
+	ResumeRow int
+	Err       error
+}
 
-		for !csvReader.IsEOF() {
-			records, err := csvReader.ReadNextBatch()
-			if err != nil {
-				logger.Errorf(i18n.Translate(ctx, "failed to read CSV batch: %v"), err)
-				break
-			}
+func (e *requeueError) Error() string { return e.Err.Error() }
+func (e *requeueError) Unwrap() error { return e.Err }
 
-			for _, row := range records {
-				logger.Infof(i18n.Translate(ctx, "processing CSV row: %v"), row)
-
-				hubID := strings.TrimSpace(row[colIdx["hub_id"]])
-				skuID := strings.TrimSpace(row[colIdx["sku_id"]])
-				qtyStr := strings.TrimSpace(row[colIdx["quantity"]])
-				priceStr := strings.TrimSpace(row[colIdx["price"]])
-				orderID := strings.TrimSpace(row[colIdx["order_id"]])
-				customerName := strings.TrimSpace(row[colIdx["customer_name"]])
-				customerIDStr := strings.TrimSpace(row[colIdx["tenant_id"]])
-
-				qty, err := strconv.Atoi(qtyStr)
-				price, perr := strconv.ParseFloat(priceStr, 64)
-				customerID, cerr := strconv.Atoi(customerIDStr)
-
-				if err != nil || qty <= 0 || perr != nil || price < 0 || cerr != nil || customerID <= 0 {
-					logger.Warnf(i18n.Translate(ctx, "invalid data in row: %v"), row)
-					invalid = append(invalid, row)
-					continue
-				}
+// fileResult summarises the outcome of processing one order file.
+type fileResult struct {
+	Counts     models.BulkJobCounts
+	ReportPath string
+}
 
-				if hubID == "" || skuID == "" {
-					logger.Warnf(i18n.Translate(ctx, "empty skuID or hubID in row: %v"), row)
-					invalid = append(invalid, row)
-					continue
-				}
+func (h *queueHandler) processFile(ctx context.Context, evt bulkOrderMessage, resumeRow int) (fileResult, error) {
+	var result fileResult
+	logger := log.DefaultLogger()
+	logger.Infof(i18n.Translate(ctx, "processing file: s3://%s/%s"), evt.Bucket, evt.Key)
 
-				if !IMS_APIS.ValidateHub(ctx, hubID) {
-					logger.Warnf(i18n.Translate(ctx, "invalid hubID in row: %v"), row)
-					invalid = append(invalid, row)
-					continue
-				}
+	getObjOutput, err := h.S3Client.GetObject(ctx, &s3.GetObjectInput{Bucket: &evt.Bucket, Key: &evt.Key})
+	if err != nil {
+		logger.Errorf(i18n.Translate(ctx, "failed to download file from S3: %v"), err)
+		return result, err
+	}
+	defer getObjOutput.Body.Close()
 
-				if !IMS_APIS.ValidateSKUOnHub(ctx, skuID) {
-					logger.Warnf(i18n.Translate(ctx, "invalid skuID on hub in row: %v"), row)
-					invalid = append(invalid, row)
-					continue
-				}
+	contentType := ""
+	if getObjOutput.ContentType != nil {
+		contentType = *getObjOutput.ContentType
+	}
+	format := detectFileFormat(evt.Key, contentType)
 
-				order := models.Order{OrderID: orderID, CustomerName: customerName, HubID: hubID, SKUID: skuID, Qty: qty, Price: price, Status: "on_hold", CustomerID: customerID}
+	tmpFile := filepath.Join(os.TempDir(), filepath.Base(evt.Key))
+	outFile, err := os.Create(tmpFile)
+	if err != nil {
+		logger.Errorf(i18n.Translate(ctx, "failed to create temp file: %v"), err)
+		return result, err
+	}
+	defer os.Remove(tmpFile)
 
-				if err := h.OrderService.UpsertOrder(ctx, order); err != nil {
-					logger.Errorf(i18n.Translate(ctx, "failed to upsert order: %v"), err)
-					invalid = append(invalid, row)
-					continue
-				}
+	_, err = io.Copy(outFile, getObjOutput.Body)
+	outFile.Close()
+	if err != nil {
+		logger.Errorf(i18n.Translate(ctx, "failed to write S3 object to file: %v"), err)
+		return result, err
+	}
 
-				event := models.OrderCreatedEvent{OrderID: order.OrderID, SKUID: order.SKUID, HubID: order.HubID, Qty: order.Qty, Price: order.Price, CustomerID: order.CustomerID}
+	reader, headers, err := newOrderRecordReader(ctx, format, tmpFile, evt.Sheet)
+	if err != nil {
+		logger.Errorf(i18n.Translate(ctx, "failed to create %s reader: %v"), format, err)
+		return result, err
+	}
+	defer reader.Close()
+	if headers != nil {
+		logger.Infof(i18n.Translate(ctx, "%s headers: %v"), format, headers)
+	}
 
-				if event.SKUID == "" || event.HubID == "" || event.Qty <= 0 {
-					logger.Warnf(i18n.Translate(ctx, "skipping Kafka event due to invalid event fields: %+v"), event)
-					continue
-				}
+	opts := processOptions{JobID: evt.JobID, ConflictPolicy: evt.ConflictPolicy}
+	var rejected []orderRecord
+	rowNumber := 0
 
-				logger.Infof(i18n.Translate(ctx, "emitting Kafka event: %+v"), event)
-				if err := h.KafkaProducer.Emit(ctx, "order.created", event); err != nil {
-					logger.Errorf(i18n.Translate(ctx, "failed to emit Kafka event for order_id %s: %v"), event.OrderID, err)
-				} else {
-					logger.Infof(i18n.Translate(ctx, "Kafka event emitted for order_id: %s"), event.OrderID)
-				}
-			}
+	// Rows recorded by an earlier attempt are counted and reported again but
+	// not reprocessed.
+	previous := make(map[int]models.BulkJobRow)
+	if resumeRow > 0 && evt.JobID != "" {
+		rows, err := h.BulkJobService.ListRows(ctx, evt.JobID, "")
+		if err != nil {
+			logger.Errorf(i18n.Translate(ctx, "failed to load recorded rows for job %s: %v"), evt.JobID, err)
+			return result, err
 		}
+		for _, row := range rows {
+			previous[row.RowNumber] = row
+		}
+		logger.Infof(i18n.Translate(ctx, "resuming job %s after row %d"), evt.JobID, resumeRow)
+	}
 
-		if len(invalid) > 0 {
-			timestamp := time.Now().Format("20060102_150405")
-			filePath := "public/invalid_orders_" + timestamp + ".csv"
+	for !reader.IsEOF() {
+		records, readErr := reader.ReadNextBatch()
 
-			dest := &csv.Destination{}
-			dest.SetFileName(filePath)
-			dest.SetUploadDirectory("public/")
-			dest.SetRandomizedFileName(false)
+		inputs := make([]models.OrderInput, 0, len(records))
+		for i, rec := range records {
+			if rec.ParseErr == nil && rowNumber+i+1 > resumeRow {
+				inputs = append(inputs, rec.Input)
+			}
+		}
+		h.Pipeline.Prefetch(ctx, inputs)
+
+		rows := make([]models.BulkJobRow, 0, len(records))
+		var requeue *requeueError
+		var unauthorized error
+		for _, rec := range records {
+			rowNumber++
+
+			if prev, ok := previous[rowNumber]; ok && rowNumber <= resumeRow {
+				result.Counts.Add(prev.Outcome)
+				if prev.Outcome == models.OrderOutcomeRejected || prev.Outcome == models.OrderOutcomeDuplicate {
+					rejected = append(rejected, rec)
+				}
+				continue
+			}
 
-			writer, err := csv.NewCommonCSVWriter(
-				csv.WithWriterHeaders(headers),
-				csv.WithWriterDestination(*dest),
-			)
-			if err != nil {
-				logger.Errorf(i18n.Translate(ctx, "failed to create CSV writer: %v"), err)
-				return err
+			row := models.BulkJobRow{JobID: evt.JobID, RowNumber: rowNumber, OrderID: rec.Input.OrderID}
+
+			if rec.ParseErr != nil {
+				logger.Warnf(i18n.Translate(ctx, "invalid data in order record: %v"), rec.ParseErr)
+				row.Outcome = models.OrderOutcomeRejected
+				row.ReasonCode = reasonCode(rec.ParseErr)
+				row.Reason = rec.ParseErr.Error()
+			} else if evt.TenantID != 0 && rec.Input.TenantID != evt.TenantID {
+				logger.Warnf(i18n.Translate(ctx, "order %s is for tenant %d, file was uploaded by tenant %d"), rec.Input.OrderID, rec.Input.TenantID, evt.TenantID)
+				row.Outcome = models.OrderOutcomeRejected
+				row.ReasonCode = errTenantMismatch.Code
+				row.Reason = errTenantMismatch.Message
+			} else {
+				order, outcome, err := h.processWithRetry(ctx, rec.Input, opts)
+				if errors.Is(err, errIMSUnavailable) {
+					requeue = &requeueError{ResumeRow: rowNumber - 1, Err: err}
+					break
+				}
+				if errors.Is(err, errIMSUnauthorized) {
+					// Every later row would fail the same way; fail the job
+					// instead of rejecting the whole file.
+					unauthorized = err
+					break
+				}
+				row.Outcome = outcome
+				row.ReasonCode = order.HoldReason
+				if err != nil {
+					row.ReasonCode = reasonCode(err)
+					row.Reason = err.Error()
+				}
 			}
-			defer writer.Close(ctx)
 
-			if err := writer.Initialize(); err != nil {
-				logger.Errorf(i18n.Translate(ctx, "failed to initialize CSV writer: %v"), err)
-				return err
+			result.Counts.Add(row.Outcome)
+			if row.Outcome == models.OrderOutcomeRejected || row.Outcome == models.OrderOutcomeDuplicate {
+				rejected = append(rejected, rec)
 			}
+			rows = append(rows, row)
+		}
 
-			if err := writer.WriteNextBatch(invalid); err != nil {
-				logger.Errorf(i18n.Translate(ctx, "failed to write invalid rows: %v"), err)
-				return err
+		if evt.JobID != "" {
+			if err := h.BulkJobService.RecordRows(ctx, rows); err != nil {
+				logger.Errorf(i18n.Translate(ctx, "failed to record row outcomes for job %s: %v"), evt.JobID, err)
 			}
+		}
 
-			logger.Infof(i18n.Translate(ctx, "invalid rows saved to CSV at: %s"), filePath)
-			publicURL := "http://localhost:8082/" + filePath
-			logger.Infof(i18n.Translate(ctx, "download invalid CSV here: %s"), publicURL)
+		if requeue != nil {
+			return result, requeue
+		}
+		if unauthorized != nil {
+			return result, unauthorized
 		}
+
+		if readErr != nil {
+			logger.Errorf(i18n.Translate(ctx, "failed to read %s batch: %v"), format, readErr)
+			break
+		}
+	}
+
+	if len(rejected) > 0 {
+		filePath, err := writeRejectedRecords(ctx, format, headers, rejected)
+		if err != nil {
+			return result, err
+		}
+		result.ReportPath = filePath
+
+		logger.Infof(i18n.Translate(ctx, "invalid rows saved to %s at: %s"), format, filePath)
+		publicURL := "http://localhost:8082/" + filePath
+		logger.Infof(i18n.Translate(ctx, "download invalid rows here: %s"), publicURL)
 	}
 
-	return nil
+	return result, nil
+}
+
+// processWithRetry runs an order through the pipeline, retrying with
+// exponential backoff while IMS is unavailable. An open circuit breaker is
+// not retried; the file is requeued instead.
+func (h *queueHandler) processWithRetry(ctx context.Context, in models.OrderInput, opts processOptions) (models.Order, models.OrderOutcome, error) {
+	retries := config.GetInt(ctx, "ims_validation.transient_retries")
+	backoff := config.GetDuration(ctx, "ims_validation.retry_backoff")
+	if backoff <= 0 {
+		backoff = defaultIMSRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		order, outcome, err := h.Pipeline.Process(ctx, in, opts)
+		if !errors.Is(err, errIMSUnavailable) || errors.Is(err, breaker.ErrOpen) || attempt >= retries {
+			return order, outcome, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return order, outcome, err
+		case <-time.After(backoff << attempt):
+		}
+	}
 }
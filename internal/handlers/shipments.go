@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/RohitGupta-omniful/OMS/services"
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+// CreateShipment ships line quantities of an order with a carrier and
+// tracking number.
+func (h *Handler) CreateShipment(c *gin.Context) {
+	var in models.ShipmentInput
+	if err := c.BindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid request body")})
+		return
+	}
+	if in.Carrier == "" || in.TrackingNumber == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "carrier and tracking_number are required")})
+		return
+	}
+	if p := in.Package; p != nil && (p.LengthCm < 0 || p.WidthCm < 0 || p.HeightCm < 0 || p.WeightKg < 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "package dimensions must not be negative")})
+		return
+	}
+
+	version, ok := expectedVersion(c)
+	if !ok {
+		return
+	}
+
+	shipment, order, err := h.ShipmentService.Create(c.Request.Context(), c.Param("order_id"), version, in)
+	h.respondShipment(c, http.StatusCreated, shipment, order, err)
+}
+
+// ListShipments returns the shipments of an order.
+func (h *Handler) ListShipments(c *gin.Context) {
+	shipments, err := h.ShipmentService.List(c.Request.Context(), c.Param("order_id"))
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "order not found")})
+	case err != nil:
+		log.Errorf(i18n.Translate(c, "failed to list shipments: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to list shipments")})
+	default:
+		c.JSON(http.StatusOK, gin.H{"shipments": shipments})
+	}
+}
+
+// DeliverShipment marks a shipment delivered.
+func (h *Handler) DeliverShipment(c *gin.Context) {
+	shipment, order, err := h.ShipmentService.Deliver(c.Request.Context(), c.Param("order_id"), c.Param("shipment_id"), time.Now())
+	h.respondShipment(c, http.StatusOK, shipment, order, err)
+}
+
+func (h *Handler) respondShipment(c *gin.Context, status int, shipment *models.Shipment, order *models.Order, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "order not found")})
+	case errors.Is(err, services.ErrShipmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(c, "shipment not found")})
+	case errors.Is(err, services.ErrInvalidShipment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, err.Error())})
+	case errors.Is(err, services.ErrInvalidTransition), errors.Is(err, services.ErrShipmentExists):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(c, err.Error())})
+	case errors.Is(err, services.ErrVersionConflict):
+		respondVersionConflict(c, err)
+	case err != nil && shipment != nil:
+		// The shipment was recorded but committing the stock failed; it is
+		// retried in the background.
+		c.JSON(http.StatusAccepted, gin.H{"shipment": shipment, "order": order, "warning": i18n.Translate(c, "inventory commit pending")})
+	case err != nil:
+		log.Errorf(i18n.Translate(c, "failed to update shipment: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to update shipment")})
+	default:
+		setETag(c, order)
+		c.JSON(status, gin.H{"shipment": shipment, "order": order})
+	}
+}
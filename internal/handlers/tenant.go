@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/RohitGupta-omniful/OMS/middleware"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+)
+
+// requestTenant returns the tenant the request was authenticated for. ok is
+// false, and a 401 has been sent, when the request carried none.
+func requestTenant(c *gin.Context) (tenantID int, ok bool) {
+	tenantID, ok = middleware.TenantID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": i18n.Translate(c, "tenant is required")})
+	}
+	return tenantID, ok
+}
+
+// claimedTenant checks a tenant_id named in the request body or path
+// against the authenticated tenant and returns the latter. A claimed ID of
+// 0 means none was given. ok is false, and a 401 or 403 has been sent, when
+// the request has no tenant or claims another one.
+func claimedTenant(c *gin.Context, claimed int) (tenantID int, ok bool) {
+	tenantID, ok = requestTenant(c)
+	if !ok {
+		return 0, false
+	}
+	if claimed != 0 && claimed != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":       i18n.Translate(c, errTenantMismatch.Error()),
+			"reason_code": models.ReasonTenantMismatch,
+		})
+		return 0, false
+	}
+	return tenantID, true
+}
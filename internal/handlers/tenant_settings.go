@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+// GetTenantSettings returns a tenant's order handling settings, falling back
+// to the defaults when none are stored.
+func (h *Handler) GetTenantSettings(c *gin.Context) {
+	tenantID, err := strconv.Atoi(c.Param("tenant_id"))
+	if err != nil || tenantID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid tenant_id")})
+		return
+	}
+	if _, ok := claimedTenant(c, tenantID); !ok {
+		return
+	}
+
+	settings, err := h.TenantSettingsService.Get(c.Request.Context(), tenantID)
+	if err != nil {
+		log.Errorf(i18n.Translate(c, "failed to load tenant settings: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to load tenant settings")})
+		return
+	}
+	if settings == nil {
+		settings = &models.TenantSettings{TenantID: tenantID, FulfilmentPolicy: models.DefaultFulfilmentPolicy}
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateTenantSettings changes a tenant's default fulfilment policy, hub
+// priority list, allocation strategies or tax settings. Omitted fields are
+// left unchanged.
+func (h *Handler) UpdateTenantSettings(c *gin.Context) {
+	tenantID, err := strconv.Atoi(c.Param("tenant_id"))
+	if err != nil || tenantID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid tenant_id")})
+		return
+	}
+	if _, ok := claimedTenant(c, tenantID); !ok {
+		return
+	}
+
+	var req models.TenantSettingsUpdate
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid request body")})
+		return
+	}
+	if req.FulfilmentPolicy != nil && !req.FulfilmentPolicy.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid fulfilment_policy")})
+		return
+	}
+	if req.AllocationStrategies != nil {
+		for _, s := range *req.AllocationStrategies {
+			if !s.IsValid() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid allocation strategy: ") + string(s)})
+				return
+			}
+		}
+	}
+
+	if req.TaxProvider != nil && !req.TaxProvider.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, "invalid tax_provider")})
+		return
+	}
+	if req.TaxRules != nil {
+		rules := *req.TaxRules
+		for i := range rules {
+			rules[i].CountryCode = strings.ToUpper(strings.TrimSpace(rules[i].CountryCode))
+			rules[i].Region = strings.TrimSpace(rules[i].Region)
+			rules[i].Category = strings.TrimSpace(rules[i].Category)
+			if err := rules[i].Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(c, err.Error())})
+				return
+			}
+		}
+	}
+
+	settings, err := h.TenantSettingsService.Update(c.Request.Context(), tenantID, req)
+	if err != nil {
+		log.Errorf(i18n.Translate(c, "failed to save tenant settings: %v"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(c, "failed to save tenant settings")})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,249 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/internal/breaker"
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+)
+
+const (
+	defaultTimeout      = 5 * time.Second
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = time.Second
+	updatePath          = "/inventory/update"
+)
+
+// TransactionType is the kind of stock movement sent to the inventory service.
+type TransactionType string
+
+const (
+	TransactionReserve TransactionType = "reserve"
+	TransactionRemove  TransactionType = "remove"
+	TransactionAdd     TransactionType = "add"
+	TransactionRelease TransactionType = "release"
+)
+
+// UpdateRequest is the body of an inventory update. ReservationID ties
+// reserve, commit and release calls for the same stock together and makes
+// them safe to retry. IdempotencyKey does the same for adds, which belong to
+// no reservation.
+type UpdateRequest struct {
+	SKUID           string          `json:"sku_id"`
+	HubID           string          `json:"hub_id"`
+	QuantityChange  int             `json:"quantity_change"`
+	TransactionType TransactionType `json:"transaction_type"`
+	ReservationID   string          `json:"reservation_id,omitempty"`
+	IdempotencyKey  string          `json:"idempotency_key,omitempty"`
+}
+
+// Reservation identifies stock set aside for an order.
+type Reservation struct {
+	ID    string
+	HubID string
+	SKUID string
+	Qty   int
+}
+
+// Client is the set of inventory operations OMS performs. Implementations
+// must be safe for concurrent use.
+type Client interface {
+	// Reserve returns the quantity actually reserved, which is less than
+	// r.Qty when the hub only has part of it.
+	Reserve(ctx context.Context, r Reservation) (int, error)
+	Commit(ctx context.Context, r Reservation) error
+	Release(ctx context.Context, r Reservation) error
+	Remove(ctx context.Context, hubID, skuID string, qty int) error
+	// Add is applied once per key, however often it is retried.
+	Add(ctx context.Context, hubID, skuID string, qty int, key string) error
+}
+
+// StatusError is returned when the inventory service answers with a non-2xx
+// status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("inventory service returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// retryable reports whether a request failing with this status may succeed
+// if sent again.
+func (e *StatusError) retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout
+}
+
+// IsRetryable reports whether a failed call may succeed if sent again, i.e.
+// it failed on the network or with a throttling or server error.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, breaker.ErrOpen) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.retryable()
+	}
+	return true
+}
+
+// HTTPClient talks to the inventory service over HTTP. Each attempt goes
+// through an optional circuit breaker.
+type HTTPClient struct {
+	baseURL      string
+	authToken    string
+	httpClient   *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+	breaker      *breaker.Breaker
+}
+
+// NewHTTPClient builds an HTTPClient from the inventory config section.
+func NewHTTPClient(ctx context.Context, cb *breaker.Breaker) (*HTTPClient, error) {
+	baseURL := strings.TrimRight(config.GetString(ctx, "inventory.service_url"), "/")
+	if baseURL == "" {
+		return nil, errors.New(i18n.Translate(ctx, "inventory.service_url is not set"))
+	}
+
+	timeout := config.GetDuration(ctx, "inventory.timeout")
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	maxRetries := config.GetInt(ctx, "inventory.max_retries")
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+	backoff := config.GetDuration(ctx, "inventory.retry_backoff")
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	return &HTTPClient{
+		baseURL:      baseURL,
+		authToken:    config.GetString(ctx, "inventory.auth_token"),
+		httpClient:   &http.Client{Timeout: timeout},
+		maxRetries:   maxRetries,
+		retryBackoff: backoff,
+		breaker:      cb,
+	}, nil
+}
+
+// reserveResponse is the inventory service's answer to a reservation.
+// ReservedQty is omitted by services that only reserve in full.
+type reserveResponse struct {
+	ReservedQty *int `json:"reserved_quantity"`
+}
+
+// Reserve sets stock aside for an order without removing it and reports how
+// much was reserved.
+func (c *HTTPClient) Reserve(ctx context.Context, r Reservation) (int, error) {
+	var resp reserveResponse
+	if err := c.update(ctx, reservationUpdate(r, TransactionReserve), &resp); err != nil {
+		return 0, err
+	}
+	if resp.ReservedQty == nil {
+		return r.Qty, nil
+	}
+	return min(*resp.ReservedQty, r.Qty), nil
+}
+
+// Commit turns a reservation into a permanent deduction, e.g. on shipment.
+func (c *HTTPClient) Commit(ctx context.Context, r Reservation) error {
+	return c.update(ctx, reservationUpdate(r, TransactionRemove), nil)
+}
+
+// Release returns reserved stock to the available pool.
+func (c *HTTPClient) Release(ctx context.Context, r Reservation) error {
+	return c.update(ctx, reservationUpdate(r, TransactionRelease), nil)
+}
+
+// Remove deducts qty units of skuID from hubID.
+func (c *HTTPClient) Remove(ctx context.Context, hubID, skuID string, qty int) error {
+	return c.update(ctx, UpdateRequest{SKUID: skuID, HubID: hubID, QuantityChange: qty, TransactionType: TransactionRemove}, nil)
+}
+
+// Add puts qty units of skuID back into stock at hubID. Retries of the same
+// key are applied once by the inventory service.
+func (c *HTTPClient) Add(ctx context.Context, hubID, skuID string, qty int, key string) error {
+	return c.update(ctx, UpdateRequest{SKUID: skuID, HubID: hubID, QuantityChange: qty, TransactionType: TransactionAdd, IdempotencyKey: key}, nil)
+}
+
+func reservationUpdate(r Reservation, tt TransactionType) UpdateRequest {
+	return UpdateRequest{SKUID: r.SKUID, HubID: r.HubID, QuantityChange: r.Qty, TransactionType: tt, ReservationID: r.ID}
+}
+
+// update posts an inventory update, retrying network errors, throttling and
+// server errors with exponential backoff. It gives up as soon as the circuit
+// breaker opens.
+func (c *HTTPClient) update(ctx context.Context, update UpdateRequest, result interface{}) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	backoff := c.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err = c.breaker.Do(ctx, func() error { return c.post(ctx, updatePath, body, result) }, IsRetryable)
+		if err == nil {
+			return nil
+		}
+		if !IsRetryable(err) {
+			return err
+		}
+		log.Errorf(i18n.Translate(ctx, "inventory %s attempt %d failed: %v"), update.TransactionType, attempt, err)
+		if attempt >= c.maxRetries {
+			return fmt.Errorf("inventory %s failed after %d attempts: %w", update.TransactionType, attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one request and decodes a successful response into result when
+// it is not nil. The request is rebuilt from body on every call so retries
+// never send an already drained reader.
+func (c *HTTPClient) post(ctx context.Context, path string, body []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if result == nil {
+			return nil
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil || len(bytes.TrimSpace(respBody)) == 0 {
+			return err
+		}
+		return json.Unmarshal(respBody, result)
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+}
@@ -0,0 +1,250 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the MIME type of an Office Open XML workbook.
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+var ErrSheetNotFound = errors.New("sheet not found in workbook")
+
+type workbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type sharedStrings struct {
+	Items []richText `xml:"si"`
+}
+
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r richText) String() string {
+	if len(r.Runs) == 0 {
+		return r.Text
+	}
+	var sb strings.Builder
+	for _, run := range r.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+type worksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline richText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// Reader serves the rows of a single worksheet in fixed-size batches, the
+// same way the go_commons CSV reader does for CSV files.
+type Reader struct {
+	rows      [][]string
+	pos       int
+	batchSize int
+}
+
+// NewReader opens the workbook at filePath and loads the named sheet, or the
+// first sheet when sheetName is empty.
+func NewReader(filePath, sheetName string, batchSize int) (*Reader, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := resolveSheet(files, sheetName)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared sharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("worksheet %s missing from workbook", sheetPath)
+	}
+	var ws worksheet
+	if err := decodeZipXML(f, &ws); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(ws.Rows))
+	for _, row := range ws.Rows {
+		var values []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			for len(values) < col {
+				values = append(values, "")
+			}
+
+			value := c.Value
+			switch c.Type {
+			case "s":
+				idx, err := strconv.Atoi(c.Value)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, fmt.Errorf("invalid shared string reference %q in cell %s", c.Value, c.Ref)
+				}
+				value = shared.Items[idx].String()
+			case "inlineStr":
+				value = c.Inline.String()
+			}
+			values = append(values, value)
+		}
+		rows = append(rows, values)
+	}
+
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &Reader{rows: rows, batchSize: batchSize}, nil
+}
+
+// GetHeaders returns the first row of the sheet.
+func (r *Reader) GetHeaders() ([]string, error) {
+	if len(r.rows) == 0 {
+		return nil, errors.New("worksheet is empty")
+	}
+	if r.pos == 0 {
+		r.pos = 1
+	}
+	return r.rows[0], nil
+}
+
+// IsEOF reports whether all data rows have been read.
+func (r *Reader) IsEOF() bool {
+	return r.pos >= len(r.rows)
+}
+
+// ReadNextBatch returns up to batchSize data rows, padding short rows so they
+// line up with the header row.
+func (r *Reader) ReadNextBatch() ([][]string, error) {
+	if r.pos == 0 {
+		if _, err := r.GetHeaders(); err != nil {
+			return nil, err
+		}
+	}
+
+	end := r.pos + r.batchSize
+	if end > len(r.rows) {
+		end = len(r.rows)
+	}
+
+	width := len(r.rows[0])
+	batch := make([][]string, 0, end-r.pos)
+	for _, row := range r.rows[r.pos:end] {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		batch = append(batch, row)
+	}
+	r.pos = end
+	return batch, nil
+}
+
+func resolveSheet(files map[string]*zip.File, sheetName string) (string, error) {
+	wbFile, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errors.New("xl/workbook.xml missing from workbook")
+	}
+	var wb workbook
+	if err := decodeZipXML(wbFile, &wb); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", errors.New("workbook has no sheets")
+	}
+
+	rid := wb.Sheets[0].RID
+	if sheetName != "" {
+		rid = ""
+		for _, s := range wb.Sheets {
+			if strings.EqualFold(s.Name, sheetName) {
+				rid = s.RID
+				break
+			}
+		}
+		if rid == "" {
+			return "", fmt.Errorf("%w: %s", ErrSheetNotFound, sheetName)
+		}
+	}
+
+	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return "", errors.New("xl/_rels/workbook.xml.rels missing from workbook")
+	}
+	var rels relationships
+	if err := decodeZipXML(relsFile, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != rid {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", fmt.Errorf("no relationship found for sheet %s", rid)
+}
+
+func decodeZipXML(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// columnIndex converts a cell reference such as "C12" to a zero-based column index.
+func columnIndex(ref string) int {
+	col := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+	}
+	return col - 1
+}
@@ -0,0 +1,209 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	testWorkbookXML = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Orders" sheetId="1" r:id="rId1"/><sheet name="Other" sheetId="2" r:id="rId2"/></sheets></workbook>`
+
+	testWorkbookRelsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/></Relationships>`
+
+	testSharedStringsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>order_id</t></si><si><t>sku_id</t></si><si><r><t>ORD-</t></r><r><t>1</t></r></si></sst>`
+)
+
+// writeTestWorkbook zips parts into a workbook under t's temp directory.
+func writeTestWorkbook(t *testing.T, parts map[string]string) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "test.xlsx")
+	out, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for name, body := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func sheetXML(rows string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + rows + `</sheetData></worksheet>`
+}
+
+func TestNewReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		cells     string
+		sheetName string
+		want      [][]string
+	}{
+		{
+			name:  "shared strings",
+			cells: `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row><row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>42</v></c></row>`,
+			want:  [][]string{{"order_id", "sku_id"}, {"ORD-1", "42"}},
+		},
+		{
+			name:  "inline strings",
+			cells: `<row r="1"><c r="A1" t="inlineStr"><is><t>order_id</t></is></c><c r="B1" t="inlineStr"><is><r><t>sku</t></r><r><t>_id</t></r></is></c></row>`,
+			want:  [][]string{{"order_id", "sku_id"}},
+		},
+		{
+			name:  "gaps in cell references",
+			cells: `<row r="1"><c r="A1"><v>a</v></c><c r="C1"><v>c</v></c><c r="AA1"><v>aa</v></c></row>`,
+			want:  [][]string{append([]string{"a", "", "c"}, append(make([]string, 23), "aa")...)},
+		},
+		{
+			name:  "cells without references",
+			cells: `<row><c><v>a</v></c><c><v>b</v></c></row>`,
+			want:  [][]string{{"a", "b"}},
+		},
+		{
+			name:      "named sheet with absolute target",
+			cells:     `<row r="1"><c r="A1"><v>first</v></c></row>`,
+			sheetName: "Other",
+			want:      [][]string{{"second"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := writeTestWorkbook(t, map[string]string{
+				"xl/workbook.xml":            testWorkbookXML,
+				"xl/_rels/workbook.xml.rels": testWorkbookRelsXML,
+				"xl/sharedStrings.xml":       testSharedStringsXML,
+				"xl/worksheets/sheet1.xml":   sheetXML(tt.cells),
+				"xl/worksheets/sheet2.xml":   sheetXML(`<row r="1"><c r="A1"><v>second</v></c></row>`),
+			})
+			r, err := NewReader(filePath, tt.sheetName, 10)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			if !reflect.DeepEqual(r.rows, tt.want) {
+				t.Errorf("rows = %q, want %q", r.rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewReaderErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		cells     string
+		sheetName string
+		wantErr   error
+	}{
+		{name: "unknown sheet", cells: `<row r="1"><c r="A1"><v>a</v></c></row>`, sheetName: "Missing", wantErr: ErrSheetNotFound},
+		{name: "shared string out of range", cells: `<row r="1"><c r="A1" t="s"><v>9</v></c></row>`},
+		{name: "shared string not a number", cells: `<row r="1"><c r="A1" t="s"><v>x</v></c></row>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := writeTestWorkbook(t, map[string]string{
+				"xl/workbook.xml":            testWorkbookXML,
+				"xl/_rels/workbook.xml.rels": testWorkbookRelsXML,
+				"xl/sharedStrings.xml":       testSharedStringsXML,
+				"xl/worksheets/sheet1.xml":   sheetXML(tt.cells),
+				"xl/worksheets/sheet2.xml":   sheetXML(""),
+			})
+			_, err := NewReader(filePath, tt.sheetName, 10)
+			if err == nil {
+				t.Fatal("NewReader: expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReaderBatches(t *testing.T) {
+	r := &Reader{
+		rows: [][]string{
+			{"order_id", "sku_id", "quantity"},
+			{"ORD-1", "SKU-1", "2"},
+			{"ORD-2"},
+			{"ORD-3", "SKU-3"},
+		},
+		batchSize: 2,
+	}
+
+	headers, err := r.GetHeaders()
+	if err != nil {
+		t.Fatalf("GetHeaders: %v", err)
+	}
+	if want := []string{"order_id", "sku_id", "quantity"}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("headers = %q, want %q", headers, want)
+	}
+
+	batches := []struct {
+		want    [][]string
+		wantEOF bool
+	}{
+		{want: [][]string{{"ORD-1", "SKU-1", "2"}, {"ORD-2", "", ""}}},
+		{want: [][]string{{"ORD-3", "SKU-3", ""}}, wantEOF: true},
+	}
+	for i, b := range batches {
+		got, err := r.ReadNextBatch()
+		if err != nil {
+			t.Fatalf("batch %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, b.want) {
+			t.Errorf("batch %d = %q, want %q", i, got, b.want)
+		}
+		if r.IsEOF() != b.wantEOF {
+			t.Errorf("batch %d: IsEOF = %v, want %v", i, r.IsEOF(), b.wantEOF)
+		}
+	}
+}
+
+func TestReaderEmptySheet(t *testing.T) {
+	r := &Reader{batchSize: 10}
+	if _, err := r.GetHeaders(); err == nil {
+		t.Error("GetHeaders: expected an error for an empty sheet")
+	}
+	if _, err := r.ReadNextBatch(); err == nil {
+		t.Error("ReadNextBatch: expected an error for an empty sheet")
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"A1", 0},
+		{"C12", 2},
+		{"Z3", 25},
+		{"AA1", 26},
+		{"AB100", 27},
+		{"ZZ1", 701},
+		{"AAA1", 702},
+	}
+	for _, tt := range tests {
+		if got := columnIndex(tt.ref); got != tt.want {
+			t.Errorf("columnIndex(%q) = %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}
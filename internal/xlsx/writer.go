@@ -0,0 +1,116 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+	rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+	workbookXMLHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="`
+	workbookXMLTail = `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+)
+
+// WriteFile writes headers and rows as a single-sheet workbook at filePath.
+// All cells are written as inline strings so values round-trip unchanged.
+func WriteFile(filePath, sheetName string, headers []string, rows [][]string) error {
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+
+	var wb bytes.Buffer
+	wb.WriteString(workbookXMLHead)
+	if err := xml.EscapeText(&wb, []byte(sheetName)); err != nil {
+		return err
+	}
+	wb.WriteString(workbookXMLTail)
+
+	parts := []struct {
+		name string
+		body []byte
+	}{
+		{"[Content_Types].xml", []byte(contentTypesXML)},
+		{"_rels/.rels", []byte(rootRelsXML)},
+		{"xl/workbook.xml", wb.Bytes()},
+		{"xl/_rels/workbook.xml.rels", []byte(workbookRelsXML)},
+	}
+	for _, p := range parts {
+		w, err := zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(p.body); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err := writeRow(&buf, 1, headers); err != nil {
+		return err
+	}
+	for i, row := range rows {
+		if err := writeRow(&buf, i+2, row); err != nil {
+			return err
+		}
+	}
+	buf.WriteString(`</sheetData></worksheet>`)
+	if _, err := sheet.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func writeRow(buf *bytes.Buffer, rowNum int, values []string) error {
+	r := strconv.Itoa(rowNum)
+	buf.WriteString(`<row r="` + r + `">`)
+	for i, v := range values {
+		buf.WriteString(`<c r="` + columnName(i) + r + `" t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(buf, []byte(v)); err != nil {
+			return err
+		}
+		buf.WriteString(`</t></is></c>`)
+	}
+	buf.WriteString(`</row>`)
+	return nil
+}
+
+// columnName converts a zero-based column index to its letter form, e.g. 27 -> "AB".
+func columnName(idx int) string {
+	name := ""
+	for idx >= 0 {
+		name = string(rune('A'+idx%26)) + name
+		idx = idx/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,102 @@
+package xlsx
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteFileRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		sheetName string
+		headers   []string
+		rows      [][]string
+		want      [][]string
+	}{
+		{
+			name:    "values round-trip unchanged",
+			headers: []string{"order_id", "customer_name", "price"},
+			rows:    [][]string{{"ORD-1", "  Jane <Doe> & Co ", "0012.50"}},
+			want:    [][]string{{"ORD-1", "  Jane <Doe> & Co ", "0012.50"}},
+		},
+		{
+			name:      "short rows are padded to the header width",
+			sheetName: "Rejected & Held",
+			headers:   []string{"order_id", "sku_id", "reason"},
+			rows:      [][]string{{"ORD-1"}, {"ORD-2", "SKU-2", "bad"}},
+			want:      [][]string{{"ORD-1", "", ""}, {"ORD-2", "SKU-2", "bad"}},
+		},
+		{
+			name:    "no data rows",
+			headers: []string{"order_id"},
+			want:    [][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "out", "report.xlsx")
+			if err := WriteFile(filePath, tt.sheetName, tt.headers, tt.rows); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+
+			r, err := NewReader(filePath, tt.sheetName, 10)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			headers, err := r.GetHeaders()
+			if err != nil {
+				t.Fatalf("GetHeaders: %v", err)
+			}
+			if !reflect.DeepEqual(headers, tt.headers) {
+				t.Errorf("headers = %q, want %q", headers, tt.headers)
+			}
+			got, err := r.ReadNextBatch()
+			if err != nil {
+				t.Fatalf("ReadNextBatch: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRow(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeRow(&buf, 3, []string{"a<b", ""}); err != nil {
+		t.Fatalf("writeRow: %v", err)
+	}
+	want := `<row r="3">` +
+		`<c r="A3" t="inlineStr"><is><t xml:space="preserve">a&lt;b</t></is></c>` +
+		`<c r="B3" t="inlineStr"><is><t xml:space="preserve"></t></is></c>` +
+		`</row>`
+	if got := buf.String(); got != want {
+		t.Errorf("writeRow = %s, want %s", got, want)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		idx  int
+		want string
+	}{
+		{0, "A"},
+		{2, "C"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+	for _, tt := range tests {
+		if got := columnName(tt.idx); got != tt.want {
+			t.Errorf("columnName(%d) = %q, want %q", tt.idx, got, tt.want)
+		}
+		if got := columnIndex(tt.want + "1"); got != tt.idx {
+			t.Errorf("columnIndex(columnName(%d)) = %d", tt.idx, got)
+		}
+	}
+}
@@ -1,13 +1,13 @@
 package kafka
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"io"
-	"net/http"
-	"time"
+	"errors"
+	"fmt"
 
+	"github.com/RohitGupta-omniful/OMS/internal/breaker"
+	"github.com/RohitGupta-omniful/OMS/internal/inventory"
 	"github.com/RohitGupta-omniful/OMS/models"
 	"github.com/RohitGupta-omniful/OMS/services"
 	"github.com/RohitGupta-omniful/OMS/webkooks"
@@ -19,97 +19,244 @@ import (
 	"github.com/omniful/go_commons/pubsub/interceptor"
 )
 
-type InventoryUpdateRequest struct {
-	SKUID           string `json:"sku_id"`
-	HubID           string `json:"hub_id"`
-	QuantityChange  int    `json:"quantity_change"`
-	TransactionType string `json:"transaction_type"`
-}
+// InventoryUpdatedTopic carries stock changes from the inventory service.
+const InventoryUpdatedTopic = "inventory.updated"
+
+// Warehouse and carrier status topics.
+const (
+	FulfillmentPackedTopic  = "fulfillment.packed"
+	ShipmentDispatchedTopic = "shipment.dispatched"
+	ShipmentDeliveredTopic  = "shipment.delivered"
+)
 
 type OrderConsumer struct {
 	OrderService services.OrderServiceInterface
+	Reservations *services.ReservationService
+	// InventoryBreaker pauses consumption while the inventory service is
+	// unavailable. It may be nil.
+	InventoryBreaker *breaker.Breaker
 }
 
 func (oc *OrderConsumer) Process(ctx context.Context, msg *pubsub.Message) error {
 	log.Infof(i18n.Translate(ctx, "Received Kafka message - Topic: %s, Key: %s"), msg.Topic, string(msg.Key))
 
+	if oc.InventoryBreaker.IsOpen() {
+		log.Warnf(i18n.Translate(ctx, "inventory circuit breaker is open, pausing consumption"))
+		if err := oc.InventoryBreaker.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
 	var evt models.OrderCreatedEvent
 	if err := json.Unmarshal(msg.Value, &evt); err != nil {
 		log.Errorf(i18n.Translate(ctx, "Failed to unmarshal Kafka message: %v"), err)
 		return err
 	}
 
-	log.Infof(i18n.Translate(ctx, "Order Event - OrderID: %s, SKUID: %s, HubID: %s, Qty: %d"), evt.OrderID, evt.SKUID, evt.HubID, evt.Qty)
+	log.Infof(i18n.Translate(ctx, "Order Event - OrderID: %s, HubID: %s, Lines: %d"), evt.OrderID, evt.HubID, len(evt.Lines))
 
 	// Validate UUIDs
-	if _, err := uuid.Parse(evt.SKUID); err != nil {
-		log.Errorf(i18n.Translate(ctx, "Invalid SKUID in Kafka event: %v"), err)
-		return err
+	skuIDs := []string{evt.SKUID}
+	if len(evt.Lines) > 0 {
+		skuIDs = skuIDs[:0]
+		for _, line := range evt.Lines {
+			skuIDs = append(skuIDs, line.SKUID)
+		}
+	}
+	for _, skuID := range skuIDs {
+		if _, err := uuid.Parse(skuID); err != nil {
+			log.Errorf(i18n.Translate(ctx, "Invalid SKUID in Kafka event: %v"), err)
+			return err
+		}
 	}
 	if _, err := uuid.Parse(evt.HubID); err != nil {
 		log.Errorf(i18n.Translate(ctx, "Invalid HubID in Kafka event: %v"), err)
 		return err
 	}
 
-	update := InventoryUpdateRequest{
-		SKUID:           evt.SKUID,
-		HubID:           evt.HubID,
-		QuantityChange:  evt.Qty,
-		TransactionType: "remove",
+	order, err := oc.OrderService.GetOrder(ctx, evt.OrderID)
+	if err != nil {
+		log.Errorf(i18n.Translate(ctx, "Failed to load order %s: %v"), evt.OrderID, err)
+		return err
+	}
+	if order.Status != models.OrderStatusOnHold {
+		log.Infof(i18n.Translate(ctx, "Order %s is already %s, skipping reservation"), evt.OrderID, order.Status)
+		return nil
 	}
 
-	body, err := json.Marshal(update)
+	reserved, err := oc.Reservations.Reserve(ctx, *order)
+	if errors.Is(err, services.ErrInvalidTransition) || errors.Is(err, services.ErrVersionConflict) {
+		log.Infof(i18n.Translate(ctx, "Order %s changed while reserving stock, skipping"), evt.OrderID)
+		return nil
+	}
 	if err != nil {
-		log.Errorf(i18n.Translate(ctx, "Failed to marshal inventory update request: %v"), err)
+		log.Errorf(i18n.Translate(ctx, "Failed to reserve inventory for order %s: %v"), evt.OrderID, err)
+
+		var statusErr *inventory.StatusError
+		if errors.As(err, &statusErr) {
+			webkooks.NotifyTenantWebhook(ctx, int64(evt.CustomerID), evt)
+		}
+
+		// Leave the order on hold for the retry scheduler and the
+		// inventory.updated consumer instead of redelivering the event.
+		herr := oc.Reservations.Hold(ctx, evt.OrderID, order.Version, models.ReasonInventoryFailed)
+		if errors.Is(herr, services.ErrInvalidTransition) || errors.Is(herr, services.ErrVersionConflict) {
+			log.Infof(i18n.Translate(ctx, "Order %s changed while reserving stock, skipping"), evt.OrderID)
+			return nil
+		}
+		if herr != nil {
+			log.Errorf(i18n.Translate(ctx, "Failed to mark order %s for retry: %v"), evt.OrderID, herr)
+			return err
+		}
+		return nil
+	}
+
+	log.Infof(i18n.Translate(ctx, "Order %s allocated under %s policy, status updated to '%s'"), evt.OrderID, reserved.FulfilmentPolicy, reserved.Status)
+	return nil
+}
+
+// InventoryUpdatedConsumer re-attempts held orders when stock arrives for
+// their hub and SKU.
+type InventoryUpdatedConsumer struct {
+	HoldRetries *services.HoldRetryService
+}
+
+func (ic *InventoryUpdatedConsumer) Process(ctx context.Context, msg *pubsub.Message) error {
+	var evt models.InventoryUpdatedEvent
+	if err := json.Unmarshal(msg.Value, &evt); err != nil {
+		log.Errorf(i18n.Translate(ctx, "Failed to unmarshal inventory.updated message: %v"), err)
 		return err
 	}
 
-	req, err := http.NewRequest("POST", "http://localhost:8000/inventory/update", bytes.NewReader(body))
+	stockArrived := evt.QuantityChange > 0 || (evt.AvailableQuantity != nil && *evt.AvailableQuantity > 0)
+	if evt.HubID == "" || evt.SKUID == "" || !stockArrived {
+		return nil
+	}
+
+	n, err := ic.HoldRetries.RetryHubSKU(ctx, evt.HubID, evt.SKUID, models.HoldRetryTriggerInventoryUpdated)
 	if err != nil {
-		log.Errorf("Failed to create HTTP request: %v", err)
+		log.Errorf(i18n.Translate(ctx, "Failed to retry held orders for hub %s sku %s: %v"), evt.HubID, evt.SKUID, err)
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	if n > 0 {
+		log.Infof(i18n.Translate(ctx, "Moved %d held orders for hub %s sku %s to new_order"), n, evt.HubID, evt.SKUID)
+	}
+	return nil
+}
 
-	client := http.Client{Timeout: 5 * time.Second}
-	const maxRetries = 3
-	backoff := time.Second
+// FulfilmentEventConsumer advances orders on warehouse and carrier events.
+// Replayed, stale and out-of-order events are logged and dropped, so every
+// event is safe to deliver more than once.
+type FulfilmentEventConsumer struct {
+	Reservations *services.ReservationService
+	Shipments    *services.ShipmentService
+}
 
-	for i := 0; i < maxRetries; i++ {
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Errorf(i18n.Translate(ctx, "HTTP request attempt %d failed: %v"), i+1, err)
-		} else {
-			defer resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
-				log.Infof(i18n.Translate(ctx, "Inventory update succeeded"))
+func (fc *FulfilmentEventConsumer) Process(ctx context.Context, msg *pubsub.Message) error {
+	var evt models.FulfilmentEvent
+	if err := json.Unmarshal(msg.Value, &evt); err != nil {
+		log.Errorf(i18n.Translate(ctx, "Failed to unmarshal %s message: %v"), msg.Topic, err)
+		return nil
+	}
+	if evt.OrderID == "" {
+		log.Warnf(i18n.Translate(ctx, "Dropping %s event %s without order_id"), msg.Topic, evt.EventID)
+		return nil
+	}
+	if evt.OccurredAt.IsZero() {
+		// Ordering relies on occurred_at; stamping a late redelivery with the
+		// current time would let it overwrite newer state.
+		log.Warnf(i18n.Translate(ctx, "Dropping %s event %s for order %s without occurred_at"), msg.Topic, evt.EventID, evt.OrderID)
+		return nil
+	}
+	if msg.Topic == ShipmentDispatchedTopic && evt.ShipmentID == "" {
+		// A generated ID would record a replayed dispatch as a second
+		// shipment, so the ID is taken from the event instead.
+		if evt.EventID == "" {
+			log.Warnf(i18n.Translate(ctx, "Dropping %s event for order %s without shipment_id or event_id"), msg.Topic, evt.OrderID)
+			return nil
+		}
+		evt.ShipmentID = eventShipmentPrefix + evt.EventID
+	}
 
-				if err := oc.OrderService.UpdateOrderStatus(ctx, evt.OrderID, "new_order"); err != nil {
-					log.Errorf(i18n.Translate(ctx, "Failed to update order status: %v"), err)
-					return err
-				}
+	var err error
+	switch msg.Topic {
+	case FulfillmentPackedTopic:
+		_, err = fc.Reservations.Pack(ctx, evt.OrderID, evt.OccurredAt)
+	case ShipmentDispatchedTopic:
+		err = fc.dispatch(ctx, evt)
+	case ShipmentDeliveredTopic:
+		err = fc.deliver(ctx, evt)
+	default:
+		log.Warnf(i18n.Translate(ctx, "Unexpected fulfilment topic %s"), msg.Topic)
+		return nil
+	}
 
-				log.Infof(i18n.Translate(ctx, "Order %s status updated to 'new_order'"), evt.OrderID)
-				return nil
-			}
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrShipmentNotFound):
+		log.Warnf(i18n.Translate(ctx, "Dropping %s event %s for order %s: %v"), msg.Topic, evt.EventID, evt.OrderID, err)
+	case errors.Is(err, services.ErrInvalidTransition), errors.Is(err, services.ErrShipmentExists), errors.Is(err, services.ErrInvalidShipment):
+		log.Infof(i18n.Translate(ctx, "Ignoring %s event %s for order %s: %v"), msg.Topic, evt.EventID, evt.OrderID, err)
+	case errors.Is(err, errInventoryPending):
+		// The shipment is recorded and its commit is retried in the background.
+	case err != nil:
+		log.Errorf(i18n.Translate(ctx, "Failed to apply %s event %s for order %s: %v"), msg.Topic, evt.EventID, evt.OrderID, err)
+		return err
+	default:
+		log.Infof(i18n.Translate(ctx, "Applied %s event %s to order %s"), msg.Topic, evt.EventID, evt.OrderID)
+	}
+	return nil
+}
 
-			webkooks.NotifyTenantWebhook(ctx, int64(evt.CustomerID), evt)
+// eventShipmentPrefix namespaces shipment IDs derived from dispatch event IDs
+// so they cannot clash with shipment IDs sent by the warehouse.
+const eventShipmentPrefix = "event:"
 
-			respBody, _ := io.ReadAll(resp.Body)
-			log.Errorf(i18n.Translate(ctx, "Inventory service returned status %d, response: %s"), resp.StatusCode, string(respBody))
-		}
+// errInventoryPending marks a shipment recorded without its inventory commit.
+var errInventoryPending = errors.New("inventory commit pending")
 
-		if i < maxRetries-1 {
-			time.Sleep(backoff)
-			backoff *= 2
-		}
+func (fc *FulfilmentEventConsumer) dispatch(ctx context.Context, evt models.FulfilmentEvent) error {
+	in := models.ShipmentInput{
+		ShipmentID:     evt.ShipmentID,
+		ShippedAt:      &evt.OccurredAt,
+		Carrier:        evt.Carrier,
+		TrackingNumber: evt.TrackingNumber,
+		Package:        evt.Package,
+		Lines:          evt.Lines,
 	}
 
-	log.Errorf(i18n.Translate(ctx, "Failed to update inventory after %d attempts"), maxRetries)
+	var shipment *models.Shipment
+	var err error
+	if len(in.Lines) == 0 {
+		shipment, _, err = fc.Shipments.ShipRemaining(ctx, evt.OrderID, services.AnyVersion, in)
+	} else {
+		shipment, _, err = fc.Shipments.Create(ctx, evt.OrderID, services.AnyVersion, in)
+	}
+	if err != nil && shipment != nil {
+		return fmt.Errorf("%w: %v", errInventoryPending, err)
+	}
 	return err
 }
 
-func InitConsumer(ctx context.Context, topic string, orderService services.OrderServiceInterface) {
+// deliver marks a shipment delivered. A delivery reported before its
+// dispatch records the shipment from the delivery event first.
+func (fc *FulfilmentEventConsumer) deliver(ctx context.Context, evt models.FulfilmentEvent) error {
+	if evt.ShipmentID == "" {
+		return services.ErrShipmentNotFound
+	}
+
+	_, _, err := fc.Shipments.Deliver(ctx, evt.OrderID, evt.ShipmentID, evt.OccurredAt)
+	if !errors.Is(err, services.ErrShipmentNotFound) {
+		return err
+	}
+
+	if err := fc.dispatch(ctx, evt); err != nil && !errors.Is(err, errInventoryPending) {
+		return err
+	}
+	_, _, err = fc.Shipments.Deliver(ctx, evt.OrderID, evt.ShipmentID, evt.OccurredAt)
+	return err
+}
+
+func InitConsumer(ctx context.Context, topic string, orderService services.OrderServiceInterface, reservations *services.ReservationService, holdRetries *services.HoldRetryService, shipments *services.ShipmentService, inventoryBreaker *breaker.Breaker) {
 	consumer := kafka.NewConsumer(
 		kafka.WithBrokers([]string{"localhost:9092"}),
 		kafka.WithConsumerGroup("oms-service"),
@@ -119,10 +266,20 @@ func InitConsumer(ctx context.Context, topic string, orderService services.Order
 
 	consumer.SetInterceptor(interceptor.NewRelicInterceptor())
 	consumer.RegisterHandler(topic, &OrderConsumer{
-		OrderService: orderService,
+		OrderService:     orderService,
+		Reservations:     reservations,
+		InventoryBreaker: inventoryBreaker,
 	})
+	consumer.RegisterHandler(InventoryUpdatedTopic, &InventoryUpdatedConsumer{
+		HoldRetries: holdRetries,
+	})
+
+	fulfilment := &FulfilmentEventConsumer{Reservations: reservations, Shipments: shipments}
+	for _, t := range []string{FulfillmentPackedTopic, ShipmentDispatchedTopic, ShipmentDeliveredTopic} {
+		consumer.RegisterHandler(t, fulfilment)
+	}
 
-	log.Infof(i18n.Translate(ctx, "Kafka consumer subscribed to topic: %s"), topic)
+	log.Infof(i18n.Translate(ctx, "Kafka consumer subscribed to topics: %s, %s, %s, %s, %s"), topic, InventoryUpdatedTopic, FulfillmentPackedTopic, ShipmentDispatchedTopic, ShipmentDeliveredTopic)
 	go consumer.Subscribe(ctx)
 
 	select {}
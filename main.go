@@ -5,7 +5,10 @@ import (
 
 	"github.com/RohitGupta-omniful/OMS/IMS_APIS"
 	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/internal/allocation"
+	"github.com/RohitGupta-omniful/OMS/internal/breaker"
 	"github.com/RohitGupta-omniful/OMS/internal/handlers"
+	"github.com/RohitGupta-omniful/OMS/internal/inventory"
 	"github.com/RohitGupta-omniful/OMS/kafka"
 	"github.com/RohitGupta-omniful/OMS/server"
 	"github.com/RohitGupta-omniful/OMS/services"
@@ -30,12 +33,20 @@ func main() {
 		return
 	}
 
+	// Circuit breakers for downstream services
+	imsBreaker := breaker.New("ims", breaker.ConfigFrom(ctx, "circuit_breaker.ims"))
+	inventoryBreaker := breaker.New("inventory", breaker.ConfigFrom(ctx, "circuit_breaker.inventory"))
+
 	// Initialize IMS client
-	if err := IMS_APIS.InitIMSClient(ctx); err != nil {
+	imsClient, err := IMS_APIS.NewIMSClient(ctx, imsBreaker)
+	if err != nil {
 		log.Errorf(i18n.Translate(ctx, "failed_connect_ims %v"), err)
 		return
 	}
 
+	// IMS validation layer with in-process cache
+	imsValidator := IMS_APIS.NewValidator(ctx, imsClient)
+
 	// Connect to MongoDB
 	mongoURI := config.GetString(ctx, "mongodb.uri")
 	if err := db.ConnectMongoDB(ctx, mongoURI); err != nil {
@@ -43,6 +54,12 @@ func main() {
 		return
 	}
 
+	// Ensure MongoDB indexes
+	if err := db.EnsureIndexes(ctx); err != nil {
+		log.Errorf(i18n.Translate(ctx, "failed_ensure_indexes %v"), err)
+		return
+	}
+
 	// Initialize webhook collection
 	webkooks.SetWebhookCollection(db.WebhookCollection())
 
@@ -53,20 +70,56 @@ func main() {
 		return
 	}
 
+	// Order service
+	orderService := services.NewOrderService()
+
+	// Hub allocation for orders submitted without a hub
+	hubAllocator := allocation.NewEngine(ctx, imsClient, imsValidator, orderService)
+
+	// Inventory service client
+	inventoryClient, err := inventory.NewHTTPClient(ctx, inventoryBreaker)
+	if err != nil {
+		log.Errorf(i18n.Translate(ctx, "failed_init_inventory %v"), err)
+		return
+	}
+
+	// Stock reservations, with a sweeper releasing expired ones
+	reservationService := services.NewReservationService(ctx, inventoryClient)
+	go reservationService.StartExpirySweeper(ctx)
+
+	// Scheduled retries of held orders
+	holdRetryService := services.NewHoldRetryService(ctx, reservationService)
+	go holdRetryService.StartScheduler(ctx)
+
+	// Kafka producer for order.created events
+	kafkaProducer := kafka.NewProducerWithConfig("order.created", []string{"localhost:9092"})
+
+	// Shipments, publishing order.shipped and order.delivered
+	orderEventsProducer := kafka.NewProducerWithConfig("order.events", []string{"localhost:9092"})
+	shipmentService := services.NewShipmentService(reservationService, orderEventsProducer)
+	go shipmentService.StartCommitRetrier(ctx)
+
+	// Returns, restocking sellable items
+	returnService := services.NewReturnService(orderService, inventoryClient, orderEventsProducer)
+	go returnService.StartRestockRetrier(ctx)
+
+	// Order tax, from tenant tax rules or the external tax provider
+	taxService := services.NewTaxService(ctx)
+
+	// Amendments of orders that are not confirmed yet, publishing order.updated
+	amendmentService := services.NewAmendmentService(reservationService, taxService, orderEventsProducer)
+
 	// Create handler with S3 client
-	handler := handlers.NewHandler(ctx, s3Client)
+	handler := handlers.NewHandler(ctx, s3Client, orderService, kafkaProducer, imsValidator, hubAllocator, taxService, reservationService, shipmentService, returnService, amendmentService, []*breaker.Breaker{imsBreaker, inventoryBreaker})
 
 	// Initialize HTTP server
 	app := server.Initialize(ctx, handler)
 
-	// Order service
-	orderService := services.NewOrderService()
-
 	// Start CSV Processor
-	go handlers.StartCSVProcessor(ctx, *s3Client, orderService)
+	go handlers.StartCSVProcessor(ctx, *s3Client, orderService, kafkaProducer, imsValidator, hubAllocator, taxService, imsBreaker)
 
-	// Start Kafka consumer with orderService injected
-	go kafka.InitConsumer(ctx, "order.created", orderService)
+	// Start Kafka consumers for order.created and inventory.updated
+	go kafka.InitConsumer(ctx, "order.created", orderService, reservationService, holdRetryService, shipmentService, inventoryBreaker)
 
 	// Start HTTP server
 	serverName := config.GetString(ctx, "server.name")
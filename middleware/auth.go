@@ -2,11 +2,18 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// TenantHeader carries the tenant a request acts for. It is only trusted on
+// requests that pass AuthMiddleware.
+const TenantHeader = "X-Tenant-ID"
+
+const tenantIDKey = "tenant_id"
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -21,6 +28,26 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if header := c.GetHeader(TenantHeader); header != "" {
+			tenantID, err := strconv.Atoi(header)
+			if err != nil || tenantID <= 0 {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"is_valid": false,
+					"message":  "Invalid tenant",
+				})
+				c.Abort()
+				return
+			}
+			c.Set(tenantIDKey, tenantID)
+		}
+
 		c.Next()
 	}
 }
+
+// TenantID returns the tenant AuthMiddleware authenticated the request for,
+// or false when the request carried none.
+func TenantID(c *gin.Context) (int, bool) {
+	tenantID := c.GetInt(tenantIDKey)
+	return tenantID, tenantID > 0
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// Address is a postal address. CountryCode is an ISO 3166-1 alpha-2 code.
+type Address struct {
+	Name        string `json:"name,omitempty" bson:"name,omitempty"`
+	Line1       string `json:"line1" bson:"line1"`
+	Line2       string `json:"line2,omitempty" bson:"line2,omitempty"`
+	City        string `json:"city" bson:"city"`
+	State       string `json:"state,omitempty" bson:"state,omitempty"`
+	PostalCode  string `json:"postal_code,omitempty" bson:"postal_code,omitempty"`
+	CountryCode string `json:"country_code" bson:"country_code"`
+}
+
+var (
+	errAddressIncomplete = errors.New("line1, city and country_code are required")
+	errUnknownCountry    = errors.New("country_code must be an ISO 3166-1 alpha-2 code")
+	errInvalidEmail      = errors.New("customer_email is not a valid email address")
+	errInvalidPhone      = errors.New("customer_phone must be an international number such as +971501234567")
+)
+
+// postalCodeFormats are the postal code formats of countries whose postal
+// codes are required, after Normalize.
+var postalCodeFormats = map[string]*regexp.Regexp{
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BH": regexp.MustCompile(`^\d{3,4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"EG": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JO": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"KW": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"OM": regexp.MustCompile(`^\d{3}$`),
+	"PK": regexp.MustCompile(`^\d{5}$`),
+	"SA": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"SG": regexp.MustCompile(`^\d{6}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// genericPostalCode bounds postal codes of countries without a known format.
+var genericPostalCode = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`)
+
+var e164 = regexp.MustCompile(`^\+[1-9]\d{6,14}$`)
+
+// countryCodes lists the ISO 3166-1 alpha-2 country codes.
+var countryCodes = strings.Fields(`
+	AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ
+	BL BM BN BO BQ BR BS BT BV BW BY BZ CA CC CD CF CG CH CI CK CL CM CN CO CR
+	CU CV CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR
+	GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU
+	ID IE IL IM IN IO IQ IR IS IT JE JM JO JP KE KG KH KI KM KN KP KR KW KY KZ
+	LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO MP MQ
+	MR MS MT MU MV MW MX MY MZ NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF
+	PG PH PK PL PM PN PR PS PT PW PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI
+	SJ SK SL SM SN SO SR SS ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM TN TO TR
+	TT TV TW TZ UA UG UM US UY UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW`)
+
+// IsEmpty reports whether no field of the address is set.
+func (a Address) IsEmpty() bool {
+	return a == Address{}
+}
+
+// Normalize trims the address and upper-cases its country and postal codes.
+func (a *Address) Normalize() {
+	a.Name = strings.TrimSpace(a.Name)
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	a.State = strings.TrimSpace(a.State)
+	a.PostalCode = strings.ToUpper(strings.TrimSpace(a.PostalCode))
+	a.CountryCode = strings.ToUpper(strings.TrimSpace(a.CountryCode))
+}
+
+// Validate checks a normalized address for its required fields, a known
+// country code and a postal code in that country's format. The postal code
+// is required for countries with a known format.
+func (a Address) Validate() error {
+	if a.Line1 == "" || a.City == "" || a.CountryCode == "" {
+		return errAddressIncomplete
+	}
+	if !containsCode(countryCodes, a.CountryCode) {
+		return errUnknownCountry
+	}
+
+	format, known := postalCodeFormats[a.CountryCode]
+	switch {
+	case known && !format.MatchString(a.PostalCode):
+		return fmt.Errorf("postal_code is not a valid %s postal code", a.CountryCode)
+	case !known && a.PostalCode != "" && !genericPostalCode.MatchString(a.PostalCode):
+		return errors.New("postal_code is not a valid postal code")
+	}
+	return nil
+}
+
+// NormalizePhone strips spaces, dashes, dots and brackets from a phone
+// number and turns a leading 00 into +.
+func NormalizePhone(phone string) string {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, phone)
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+	return phone
+}
+
+// ValidateContact checks a customer email and a normalized phone number.
+// Either may be empty.
+func ValidateContact(email, phone string) error {
+	if email != "" {
+		parsed, err := mail.ParseAddress(email)
+		if err != nil || parsed.Address != email {
+			return errInvalidEmail
+		}
+	}
+	if phone != "" && !e164.MatchString(phone) {
+		return errInvalidPhone
+	}
+	return nil
+}
+
+func containsCode(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
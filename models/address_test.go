@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAddressNormalize(t *testing.T) {
+	a := Address{Name: " Jane ", Line1: " 1 Main St ", City: " Dubai ", State: " dubai ", PostalCode: " sw1a 1aa ", CountryCode: " gb "}
+	a.Normalize()
+
+	want := Address{Name: "Jane", Line1: "1 Main St", City: "Dubai", State: "dubai", PostalCode: "SW1A 1AA", CountryCode: "GB"}
+	if a != want {
+		t.Errorf("Normalize = %+v, want %+v", a, want)
+	}
+}
+
+func TestAddressValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		address Address
+		wantErr bool
+		err     error
+	}{
+		{name: "US ZIP", address: Address{Line1: "1 Main St", City: "Springfield", PostalCode: "62704", CountryCode: "US"}},
+		{name: "US ZIP+4", address: Address{Line1: "1 Main St", City: "Springfield", PostalCode: "62704-1234", CountryCode: "US"}},
+		{name: "GB postcode", address: Address{Line1: "10 Downing St", City: "London", PostalCode: "SW1A 2AA", CountryCode: "GB"}},
+		{name: "no format, no postal code", address: Address{Line1: "Sheikh Zayed Rd", City: "Dubai", CountryCode: "AE"}},
+		{name: "no format, generic postal code", address: Address{Line1: "1 Rue", City: "Monaco", PostalCode: "98000", CountryCode: "MC"}},
+		{name: "missing line1", address: Address{City: "Dubai", CountryCode: "AE"}, wantErr: true, err: errAddressIncomplete},
+		{name: "missing city", address: Address{Line1: "1 Main St", CountryCode: "AE"}, wantErr: true, err: errAddressIncomplete},
+		{name: "missing country", address: Address{Line1: "1 Main St", City: "Dubai"}, wantErr: true, err: errAddressIncomplete},
+		{name: "unknown country", address: Address{Line1: "1 Main St", City: "Nowhere", CountryCode: "XX"}, wantErr: true, err: errUnknownCountry},
+		{name: "lower-case country", address: Address{Line1: "1 Main St", City: "Dubai", CountryCode: "ae"}, wantErr: true, err: errUnknownCountry},
+		{name: "required postal code missing", address: Address{Line1: "1 Main St", City: "Springfield", CountryCode: "US"}, wantErr: true},
+		{name: "postal code in the wrong format", address: Address{Line1: "1 Main St", City: "Berlin", PostalCode: "1234", CountryCode: "DE"}, wantErr: true},
+		{name: "generic postal code too long", address: Address{Line1: "1 Rue", City: "Monaco", PostalCode: "98000000000", CountryCode: "MC"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.address.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAddressIsEmpty(t *testing.T) {
+	if !(Address{}).IsEmpty() {
+		t.Error("zero Address is not empty")
+	}
+	if (Address{State: "CA"}).IsEmpty() {
+		t.Error("Address with a state is empty")
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+	}{
+		{"+971 50 123 4567", "+971501234567"},
+		{"00971-50-123-4567", "+971501234567"},
+		{"(555) 123.4567", "5551234567"},
+		{"+14155550100", "+14155550100"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizePhone(tt.phone); got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
+
+func TestValidateContact(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		phone string
+		err   error
+	}{
+		{name: "both empty"},
+		{name: "valid", email: "jane@example.com", phone: "+971501234567"},
+		{name: "display name is not an address", email: "Jane <jane@example.com>", err: errInvalidEmail},
+		{name: "missing domain", email: "jane@", err: errInvalidEmail},
+		{name: "no plus", phone: "971501234567", err: errInvalidPhone},
+		{name: "too short", phone: "+12345", err: errInvalidPhone},
+		{name: "leading zero country code", phone: "+0501234567", err: errInvalidPhone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateContact(tt.email, tt.phone); !errors.Is(err, tt.err) {
+				t.Errorf("ValidateContact(%q, %q) = %v, want %v", tt.email, tt.phone, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// AllocationStrategy chooses the fulfilling hub for orders submitted without
+// a hub_id.
+type AllocationStrategy string
+
+const (
+	// AllocationFullStock prefers hubs that stock every line in full.
+	AllocationFullStock AllocationStrategy = "full_stock"
+	// AllocationTenantPriority prefers the first hub in the tenant's priority list.
+	AllocationTenantPriority AllocationStrategy = "tenant_priority"
+	// AllocationNearest prefers the hub nearest the shipping coordinates.
+	AllocationNearest AllocationStrategy = "nearest"
+	// AllocationLeastLoaded prefers the hub with the fewest open orders.
+	AllocationLeastLoaded AllocationStrategy = "least_loaded"
+	// AllocationFirstAvailable is recorded when no strategy had a preference
+	// and the first hub listed by IMS was used.
+	AllocationFirstAvailable AllocationStrategy = "first_available"
+	// AllocationSplit is recorded on split orders and their children.
+	AllocationSplit AllocationStrategy = "split"
+)
+
+// DefaultAllocationStrategies is the order strategies are applied in when
+// neither the tenant nor the hub_allocation.strategies config sets one.
+var DefaultAllocationStrategies = []AllocationStrategy{
+	AllocationFullStock,
+	AllocationTenantPriority,
+	AllocationNearest,
+	AllocationLeastLoaded,
+}
+
+func (s AllocationStrategy) IsValid() bool {
+	switch s {
+	case AllocationFullStock, AllocationTenantPriority, AllocationNearest, AllocationLeastLoaded:
+		return true
+	}
+	return false
+}
+
+// Coordinates is a point in decimal degrees.
+type Coordinates struct {
+	Latitude  float64 `json:"latitude" bson:"latitude"`
+	Longitude float64 `json:"longitude" bson:"longitude"`
+}
+
+// HubAllocation records how OMS chose the hub of an order submitted without one.
+type HubAllocation struct {
+	HubID       string             `json:"hub_id" bson:"hub_id"`
+	Strategy    AllocationStrategy `json:"strategy" bson:"strategy"`
+	Reason      string             `json:"reason" bson:"reason"`
+	Candidates  int                `json:"candidates" bson:"candidates"`
+	AllocatedAt time.Time          `json:"allocated_at" bson:"allocated_at"`
+}
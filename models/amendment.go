@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// OrderAmendmentInput changes an order that has not been confirmed yet.
+// HubID moves the order to another hub. Each line change names an existing
+// line by LineNumber, or adds a line when LineNumber is 0.
+type OrderAmendmentInput struct {
+	HubID *string               `json:"hub_id,omitempty"`
+	Lines []OrderLineAmendInput `json:"lines,omitempty"`
+}
+
+// OrderLineAmendInput changes the quantity or price of a line, removes it,
+// or adds a new line with SKUID, Qty and Price. Discount, Tax and
+// TaxCategory only apply to new lines, as on a submitted order.
+type OrderLineAmendInput struct {
+	LineNumber  int      `json:"line_number,omitempty"`
+	SKUID       string   `json:"sku_id,omitempty"`
+	Qty         *int     `json:"quantity,omitempty"`
+	Price       *float64 `json:"price,omitempty"`
+	Discount    *float64 `json:"discount,omitempty"`
+	Tax         *float64 `json:"tax,omitempty"`
+	TaxCategory string   `json:"tax_category,omitempty"`
+	Remove      bool     `json:"remove,omitempty"`
+}
+
+// OrderAmendment records one accepted amendment of an order as the list of
+// fields it changed. Version counts the amendments of the order from 1.
+type OrderAmendment struct {
+	AmendmentID string        `json:"amendment_id" bson:"amendment_id"`
+	OrderID     string        `json:"order_id" bson:"order_id"`
+	CustomerID  int           `json:"customer_id" bson:"customer_id"`
+	Version     int           `json:"version" bson:"version"`
+	Changes     []FieldChange `json:"changes" bson:"changes"`
+	CreatedAt   time.Time     `json:"created_at" bson:"created_at"`
+}
+
+// FieldChange is one changed field of an order. Line fields are named
+// lines[<line_number>].<field>; added and removed lines are named
+// lines[<line_number>] with a nil From or To.
+type FieldChange struct {
+	Field string `json:"field" bson:"field"`
+	From  any    `json:"from" bson:"from"`
+	To    any    `json:"to" bson:"to"`
+}
+
+// Changed reports whether the amendment changed field.
+func (a OrderAmendment) Changed(field string) bool {
+	for _, c := range a.Changes {
+		if c.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderUpdatedEvent is published as order.updated when an order is amended.
+type OrderUpdatedEvent struct {
+	Event         string        `json:"event"`
+	OrderID       string        `json:"order_id"`
+	ParentOrderID string        `json:"parent_order_id,omitempty"`
+	CustomerID    int           `json:"customer_id"`
+	HubID         string        `json:"hub_id"`
+	Status        string        `json:"status"`
+	Version       int           `json:"version"`
+	Changes       []FieldChange `json:"changes"`
+	OccurredAt    time.Time     `json:"occurred_at"`
+}
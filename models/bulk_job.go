@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+const (
+	BulkJobStatusQueued     = "queued"
+	BulkJobStatusProcessing = "processing"
+	BulkJobStatusCompleted  = "completed"
+	BulkJobStatusFailed     = "failed"
+)
+
+// BulkJob tracks one submission of an S3 order file. Jobs are keyed by the
+// object's checksum per tenant so the same file is not imported twice.
+type BulkJob struct {
+	JobID    string `json:"job_id" bson:"job_id"`
+	TenantID int    `json:"tenant_id" bson:"tenant_id"`
+	Bucket   string `json:"bucket" bson:"bucket"`
+	Key      string `json:"key" bson:"key"`
+	Sheet    string `json:"sheet,omitempty" bson:"sheet,omitempty"`
+	Checksum string `json:"checksum" bson:"checksum"`
+	// ActiveChecksum repeats Checksum while the job counts towards
+	// de-duplication: it is empty for forced jobs and cleared when the job
+	// fails. A unique index on it stops concurrent uploads of one file.
+	ActiveChecksum string         `json:"-" bson:"active_checksum,omitempty"`
+	Forced         bool           `json:"forced" bson:"forced"`
+	ConflictPolicy ConflictPolicy `json:"conflict_policy" bson:"conflict_policy"`
+	Status         string         `json:"status" bson:"status"`
+	ReportPath     string         `json:"report_path,omitempty" bson:"report_path,omitempty"`
+	Error          string         `json:"error,omitempty" bson:"error,omitempty"`
+	Attempts       int            `json:"attempts" bson:"attempts"`
+	CreatedAt      time.Time      `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" bson:"updated_at"`
+
+	// ResumeRow is the last row already recorded when a job was requeued
+	// after a transient failure; processing resumes after it.
+	ResumeRow int `json:"resume_row,omitempty" bson:"resume_row,omitempty"`
+
+	BulkJobCounts `bson:",inline"`
+}
+
+// BulkJobCounts tallies row outcomes for a job.
+type BulkJobCounts struct {
+	CreatedCount  int `json:"created_count" bson:"created_count"`
+	UpdatedCount  int `json:"updated_count" bson:"updated_count"`
+	SkippedCount  int `json:"skipped_count" bson:"skipped_count"`
+	RejectedCount int `json:"rejected_count" bson:"rejected_count"`
+}
+
+// Add counts a single row outcome.
+func (c *BulkJobCounts) Add(outcome OrderOutcome) {
+	switch outcome {
+	case OrderOutcomeCreated:
+		c.CreatedCount++
+	case OrderOutcomeUpdated:
+		c.UpdatedCount++
+	case OrderOutcomeSkipped:
+		c.SkippedCount++
+	default:
+		c.RejectedCount++
+	}
+}
+
+// BulkJobRow is the per-row entry of a job report.
+type BulkJobRow struct {
+	JobID      string       `json:"job_id" bson:"job_id"`
+	RowNumber  int          `json:"row_number" bson:"row_number"`
+	OrderID    string       `json:"order_id" bson:"order_id"`
+	Outcome    OrderOutcome `json:"outcome" bson:"outcome"`
+	ReasonCode string       `json:"reason_code,omitempty" bson:"reason_code,omitempty"`
+	Reason     string       `json:"reason,omitempty" bson:"reason,omitempty"`
+}
+
+// EmittedEvent marks that an event was published for an order within a job.
+type EmittedEvent struct {
+	TenantID  int       `json:"tenant_id" bson:"tenant_id"`
+	OrderID   string    `json:"order_id" bson:"order_id"`
+	JobID     string    `json:"job_id" bson:"job_id"`
+	Event     string    `json:"event" bson:"event"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// ConflictPolicy decides what happens when an imported order_id already exists.
+type ConflictPolicy string
+
+const (
+	// ConflictSkipExisting leaves existing orders untouched.
+	ConflictSkipExisting ConflictPolicy = "skip_existing"
+	// ConflictUpdateOnHold overwrites existing orders only while they are on_hold.
+	ConflictUpdateOnHold ConflictPolicy = "update_on_hold"
+	// ConflictRejectDuplicate rejects the row as a duplicate.
+	ConflictRejectDuplicate ConflictPolicy = "reject_duplicate"
+)
+
+// DefaultConflictPolicy is used when an upload does not choose a policy.
+const DefaultConflictPolicy = ConflictUpdateOnHold
+
+func (p ConflictPolicy) IsValid() bool {
+	switch p {
+	case ConflictSkipExisting, ConflictUpdateOnHold, ConflictRejectDuplicate:
+		return true
+	}
+	return false
+}
+
+// OrderOutcome is the result of importing a single order.
+type OrderOutcome string
+
+const (
+	OrderOutcomeCreated   OrderOutcome = "created"
+	OrderOutcomeUpdated   OrderOutcome = "updated"
+	OrderOutcomeSkipped   OrderOutcome = "skipped"
+	OrderOutcomeDuplicate OrderOutcome = "duplicate"
+	OrderOutcomeRejected  OrderOutcome = "rejected"
+)
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Customer is a shopper of a tenant, identified within the tenant by the
+// tenant's own ExternalID. Imports create customers on first sight and keep
+// their contact details current.
+type Customer struct {
+	CustomerID     string    `json:"customer_id" bson:"customer_id"`
+	TenantID       int       `json:"tenant_id" bson:"tenant_id"`
+	ExternalID     string    `json:"external_id" bson:"external_id"`
+	Name           string    `json:"name,omitempty" bson:"name,omitempty"`
+	Email          string    `json:"email,omitempty" bson:"email,omitempty"`
+	Phone          string    `json:"phone,omitempty" bson:"phone,omitempty"`
+	DefaultAddress *Address  `json:"default_address,omitempty" bson:"default_address,omitempty"`
+	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// OrderCustomer links an order to the customer who placed it.
+type OrderCustomer struct {
+	ID         string `json:"id" bson:"id"`
+	ExternalID string `json:"external_id" bson:"external_id"`
+}
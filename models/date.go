@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// ParseDate accepts an RFC 3339 timestamp in either case, such as
+// 2024-01-01T10:00:00Z or 2024-01-01t10:00:00z, or a plain YYYY-MM-DD
+// date, taken as midnight UTC.
+func ParseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(value)); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// Date is a date submitted with an order, in any format ParseDate accepts.
+type Date struct {
+	time.Time
+}
+
+// UnmarshalJSON parses a JSON string with ParseDate.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	t, err := ParseDate(value)
+	if err != nil {
+		return err
+	}
+	d.Time = t
+	return nil
+}
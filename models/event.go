@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type OrderCreatedEvent struct {
 	OrderID    string  `json:"order_id" bson:"order_id"`
 	SKUID      string  `json:"sku_id" bson:"sku_id"`
@@ -8,4 +10,55 @@ type OrderCreatedEvent struct {
 	Price      float64 `json:"price" bson:"price"`
 	Status     string  `json:"status,omitempty" bson:"status,omitempty"`
 	CustomerID int     `json:"customer_id" bson:"customer_id"`
+	// ParentOrderID is set for child orders of a split order.
+	ParentOrderID string `json:"parent_order_id,omitempty" bson:"parent_order_id,omitempty"`
+
+	Lines    []OrderLineInput `json:"lines,omitempty" bson:"lines,omitempty"`
+	Currency string           `json:"currency,omitempty" bson:"currency,omitempty"`
+	Totals   *OrderTotals     `json:"totals,omitempty" bson:"totals,omitempty"`
+
+	ShippingAddress *Address `json:"shipping_address,omitempty" bson:"shipping_address,omitempty"`
+	BillingAddress  *Address `json:"billing_address,omitempty" bson:"billing_address,omitempty"`
+	CustomerEmail   string   `json:"customer_email,omitempty" bson:"customer_email,omitempty"`
+	CustomerPhone   string   `json:"customer_phone,omitempty" bson:"customer_phone,omitempty"`
+}
+
+// OrderStatusChangedEvent is sent to tenant webhooks when an order is packed
+// or a child of a split order changes status. For children it carries the
+// parent's rolled up status.
+type OrderStatusChangedEvent struct {
+	Event         string `json:"event"`
+	OrderID       string `json:"order_id"`
+	ParentOrderID string `json:"parent_order_id,omitempty"`
+	HubID         string `json:"hub_id"`
+	Status        string `json:"status"`
+	ParentStatus  string `json:"parent_status,omitempty"`
+	CustomerID    int    `json:"customer_id"`
+
+	ShippingAddress *Address `json:"shipping_address,omitempty"`
+	CustomerEmail   string   `json:"customer_email,omitempty"`
+	CustomerPhone   string   `json:"customer_phone,omitempty"`
+}
+
+// FulfilmentEvent is a status update published by a warehouse or carrier on
+// the fulfillment.packed, shipment.dispatched or shipment.delivered topics.
+// Lines may be omitted from dispatch events to ship everything allocated.
+type FulfilmentEvent struct {
+	EventID        string              `json:"event_id"`
+	OrderID        string              `json:"order_id"`
+	ShipmentID     string              `json:"shipment_id,omitempty"`
+	Carrier        string              `json:"carrier,omitempty"`
+	TrackingNumber string              `json:"tracking_number,omitempty"`
+	Package        *PackageDimensions  `json:"package,omitempty"`
+	Lines          []ShipmentLineInput `json:"lines,omitempty"`
+	OccurredAt     time.Time           `json:"occurred_at"`
+}
+
+// InventoryUpdatedEvent is published by the inventory service when stock for
+// a SKU at a hub changes.
+type InventoryUpdatedEvent struct {
+	HubID             string `json:"hub_id" bson:"hub_id"`
+	SKUID             string `json:"sku_id" bson:"sku_id"`
+	QuantityChange    int    `json:"quantity_change" bson:"quantity_change"`
+	AvailableQuantity *int   `json:"available_quantity,omitempty" bson:"available_quantity,omitempty"`
 }
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// FulfilmentPolicy decides what happens when only part of an order's
+// quantity is in stock.
+type FulfilmentPolicy string
+
+const (
+	// FulfilmentAllowPartial reserves what is available and backorders the rest.
+	FulfilmentAllowPartial FulfilmentPolicy = "allow_partial"
+	// FulfilmentShipComplete reserves nothing until every line can be
+	// reserved in full; until then the whole order is backordered.
+	FulfilmentShipComplete FulfilmentPolicy = "ship_complete"
+	// FulfilmentCancelUnavailable reserves what is available and cancels the rest.
+	FulfilmentCancelUnavailable FulfilmentPolicy = "cancel_unavailable"
+)
+
+// DefaultFulfilmentPolicy is used when neither the order nor its tenant
+// chooses a policy. It matches the original all-or-nothing behaviour.
+const DefaultFulfilmentPolicy = FulfilmentShipComplete
+
+func (p FulfilmentPolicy) IsValid() bool {
+	switch p {
+	case FulfilmentAllowPartial, FulfilmentShipComplete, FulfilmentCancelUnavailable:
+		return true
+	}
+	return false
+}
+
+// TenantSettings holds a tenant's order handling preferences.
+type TenantSettings struct {
+	TenantID         int              `json:"tenant_id" bson:"tenant_id"`
+	FulfilmentPolicy FulfilmentPolicy `json:"fulfilment_policy" bson:"fulfilment_policy"`
+	// HubPriority lists the tenant's hubs in order of preference for the
+	// tenant_priority allocation strategy.
+	HubPriority []string `json:"hub_priority,omitempty" bson:"hub_priority,omitempty"`
+	// AllocationStrategies overrides the order hub allocation strategies
+	// are applied in.
+	AllocationStrategies []AllocationStrategy `json:"allocation_strategies,omitempty" bson:"allocation_strategies,omitempty"`
+	// SplitOrders lets orders without a hub_id be split across hubs when no
+	// single hub stocks every line.
+	SplitOrders bool `json:"split_orders" bson:"split_orders"`
+	// TaxProvider decides how order tax is worked out; empty means
+	// submitted. TaxRules are used by the rules provider.
+	TaxProvider TaxProvider `json:"tax_provider,omitempty" bson:"tax_provider,omitempty"`
+	TaxRules    []TaxRule   `json:"tax_rules,omitempty" bson:"tax_rules,omitempty"`
+	UpdatedAt   time.Time   `json:"updated_at" bson:"updated_at"`
+}
+
+// TenantSettingsUpdate is a partial update of TenantSettings. Nil fields are
+// left unchanged.
+type TenantSettingsUpdate struct {
+	FulfilmentPolicy     *FulfilmentPolicy     `json:"fulfilment_policy,omitempty"`
+	HubPriority          *[]string             `json:"hub_priority,omitempty"`
+	AllocationStrategies *[]AllocationStrategy `json:"allocation_strategies,omitempty"`
+	SplitOrders          *bool                 `json:"split_orders,omitempty"`
+	TaxProvider          *TaxProvider          `json:"tax_provider,omitempty"`
+	TaxRules             *[]TaxRule            `json:"tax_rules,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	HoldRetryTriggerScheduler        = "scheduler"
+	HoldRetryTriggerInventoryUpdated = "inventory.updated"
+)
+
+const (
+	HoldRetryOutcomeReserved    = "reserved"
+	HoldRetryOutcomeBackordered = "backordered"
+	HoldRetryOutcomeFailed      = "failed"
+	HoldRetryOutcomeSkipped     = "skipped"
+)
+
+// HoldRetryAttempt records one attempt to move an on_hold order forward.
+type HoldRetryAttempt struct {
+	OrderID     string    `json:"order_id" bson:"order_id"`
+	HubID       string    `json:"hub_id" bson:"hub_id"`
+	SKUID       string    `json:"sku_id" bson:"sku_id"`
+	Trigger     string    `json:"trigger" bson:"trigger"`
+	Outcome     string    `json:"outcome" bson:"outcome"`
+	Error       string    `json:"error,omitempty" bson:"error,omitempty"`
+	AttemptedAt time.Time `json:"attempted_at" bson:"attempted_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyRecord remembers the outcome of a request sent with an
+// Idempotency-Key header so that retries return the original result.
+type IdempotencyRecord struct {
+	Key         string    `json:"key" bson:"key"`
+	TenantID    int       `json:"tenant_id" bson:"tenant_id"`
+	RequestHash string    `json:"request_hash" bson:"request_hash"`
+	Status      string    `json:"status" bson:"status"`
+	OrderID     string    `json:"order_id,omitempty" bson:"order_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// Money is an amount in the minor units of a currency, such as cents, so
+// that sums are exact.
+type Money int64
+
+// maxMoney is the largest amount OMS stores, line or order total. It is far
+// enough below the int64 limit that sums of checked amounts cannot overflow.
+const maxMoney = Money(1e13)
+
+var (
+	errNotFinite            = errors.New("amounts must be finite numbers")
+	errNegativeAmount       = errors.New("amounts must not be negative")
+	errDiscountExceedsGross = errors.New("a line discount must not exceed quantity times price")
+	errAmountTooLarge       = errors.New("amount is too large")
+	errAmountDecimals       = errors.New("amount has more decimals than its currency")
+)
+
+// OrderTotals are the order-level amounts in the order's currency.
+// GrandTotal is Subtotal - Discount + Shipping + Tax.
+type OrderTotals struct {
+	// Subtotal is the sum of quantity times unit price over the lines.
+	Subtotal   Money `json:"subtotal" bson:"subtotal"`
+	Discount   Money `json:"discount" bson:"discount"`
+	Shipping   Money `json:"shipping" bson:"shipping"`
+	Tax        Money `json:"tax" bson:"tax"`
+	GrandTotal Money `json:"grand_total" bson:"grand_total"`
+}
+
+// currencyCodes lists the active ISO 4217 currency codes.
+var currencyCodes = strings.Fields(`
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB
+	BRL BSD BTN BWP BYN BZD CAD CDF CHF CLP CNY COP CRC CUP CVE CZK DJF DKK DOP
+	DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL HTG HUF
+	IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT LAK
+	LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MYR MZN
+	NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF
+	SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND
+	TOP TRY TTD TWD TZS UAH UGX USD UYU UZS VES VND VUV WST XAF XCD XOF XPF YER
+	ZAR ZMW ZWL`)
+
+// currencyExponents are the number of minor unit digits of currencies that
+// do not have two.
+var currencyExponents = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+}
+
+// ValidCurrency reports whether code is an ISO 4217 currency code.
+func ValidCurrency(code string) bool {
+	return containsCode(currencyCodes, code)
+}
+
+// CurrencyExponent returns the number of minor unit digits of a currency,
+// two unless listed otherwise.
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// ToMinor converts an amount in major units, such as 12.50, to the minor
+// units of currency. Negative amounts and amounts with more decimals than
+// the currency has are rejected, as are NaN and infinities.
+func ToMinor(amount float64, currency string) (Money, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, errNotFinite
+	}
+	if amount < 0 {
+		return 0, errNegativeAmount
+	}
+	scaled := amount * math.Pow10(CurrencyExponent(currency))
+	minor := math.Round(scaled)
+	if minor > float64(maxMoney) {
+		return 0, errAmountTooLarge
+	}
+	if math.Abs(scaled-minor) > 1e-6 {
+		return 0, errAmountDecimals
+	}
+	return Money(minor), nil
+}
+
+// ToMajor converts an amount in the minor units of currency to major units.
+func ToMajor(amount Money, currency string) float64 {
+	return float64(amount) / math.Pow10(CurrencyExponent(currency))
+}
+
+// LineGross returns qty times unitPrice, failing when it would exceed the
+// largest amount OMS stores.
+func LineGross(qty int, unitPrice Money) (Money, error) {
+	if qty < 0 || unitPrice < 0 {
+		return 0, errNegativeAmount
+	}
+	if unitPrice != 0 && Money(qty) > maxMoney/unitPrice {
+		return 0, errAmountTooLarge
+	}
+	return Money(qty) * unitPrice, nil
+}
+
+// SumMoney adds non-negative amounts, failing when the sum would exceed the
+// largest amount OMS stores.
+func SumMoney(amounts ...Money) (Money, error) {
+	var sum Money
+	for _, amount := range amounts {
+		if amount < 0 {
+			return 0, errNegativeAmount
+		}
+		if amount > maxMoney-sum {
+			return 0, errAmountTooLarge
+		}
+		sum += amount
+	}
+	return sum, nil
+}
+
+// CheckAmounts checks that the lines of an order and shipping can be
+// totalled by ComputeTotals without exceeding the largest amount OMS stores,
+// and that no line is discounted below zero.
+func (o *Order) CheckAmounts(shipping Money) error {
+	total, err := SumMoney(shipping)
+	if err != nil {
+		return err
+	}
+	for _, line := range o.Lines {
+		gross, err := LineGross(line.Qty, line.UnitPrice)
+		if err != nil {
+			return err
+		}
+		if line.Discount < 0 || line.Discount > gross {
+			return errDiscountExceedsGross
+		}
+		if total, err = SumMoney(total, gross, line.Tax); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProratedTotal returns the share of the line total for qty of the line's
+// quantity, rounded down to the minor unit. qty is capped at the quantity.
+func (l OrderLine) ProratedTotal(qty int) Money {
+	if qty <= 0 || l.Qty <= 0 || l.Total <= 0 {
+		return 0
+	}
+	if qty >= l.Qty {
+		return l.Total
+	}
+	hi, lo := bits.Mul64(uint64(l.Total), uint64(qty))
+	share, _ := bits.Div64(hi, lo, uint64(l.Qty))
+	return Money(share)
+}
+
+// LineTotal returns quantity times unit price less the discount plus tax.
+func (l OrderLine) LineTotal() Money {
+	return Money(l.Qty)*l.UnitPrice - l.Discount + l.Tax
+}
+
+// ComputeTotals sets the total of every line and the order totals from the
+// lines and shipping, which must have passed CheckAmounts.
+func (o *Order) ComputeTotals(shipping Money) {
+	totals := OrderTotals{Shipping: shipping}
+	for i := range o.Lines {
+		line := &o.Lines[i]
+		line.Total = line.LineTotal()
+		totals.Subtotal += Money(line.Qty) * line.UnitPrice
+		totals.Discount += line.Discount
+		totals.Tax += line.Tax
+	}
+	totals.GrandTotal = totals.Subtotal - totals.Discount + totals.Shipping + totals.Tax
+	o.Totals = &totals
+}
@@ -0,0 +1,243 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestToMinor(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		currency string
+		want     Money
+		wantErr  error
+	}{
+		{name: "two decimals", amount: 12.5, currency: "USD", want: 1250},
+		{name: "float noise is rounded", amount: 0.1 + 0.2, currency: "USD", want: 30},
+		{name: "zero decimals", amount: 1500, currency: "JPY", want: 1500},
+		{name: "three decimals", amount: 1.234, currency: "KWD", want: 1234},
+		{name: "zero", amount: 0, currency: "EUR", want: 0},
+		{name: "largest amount", amount: 1e11, currency: "USD", want: maxMoney},
+		{name: "too many decimals", amount: 12.345, currency: "USD", wantErr: errAmountDecimals},
+		{name: "decimals on a whole currency", amount: 1.5, currency: "JPY", wantErr: errAmountDecimals},
+		{name: "negative", amount: -1, currency: "USD", wantErr: errNegativeAmount},
+		{name: "too large", amount: 1e11 + 1, currency: "USD", wantErr: errAmountTooLarge},
+		{name: "NaN", amount: math.NaN(), currency: "USD", wantErr: errNotFinite},
+		{name: "positive infinity", amount: math.Inf(1), currency: "USD", wantErr: errNotFinite},
+		{name: "negative infinity", amount: math.Inf(-1), currency: "USD", wantErr: errNotFinite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToMinor(tt.amount, tt.currency)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ToMinor(%v, %s) error = %v, want %v", tt.amount, tt.currency, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToMinor(%v, %s) = %d, want %d", tt.amount, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrency(t *testing.T) {
+	tests := []struct {
+		code     string
+		valid    bool
+		exponent int
+	}{
+		{code: "USD", valid: true, exponent: 2},
+		{code: "JPY", valid: true, exponent: 0},
+		{code: "BHD", valid: true, exponent: 3},
+		{code: "usd", valid: false, exponent: 2},
+		{code: "XYZ", valid: false, exponent: 2},
+		{code: "", valid: false, exponent: 2},
+	}
+	for _, tt := range tests {
+		if got := ValidCurrency(tt.code); got != tt.valid {
+			t.Errorf("ValidCurrency(%q) = %v, want %v", tt.code, got, tt.valid)
+		}
+		if got := CurrencyExponent(tt.code); got != tt.exponent {
+			t.Errorf("CurrencyExponent(%q) = %d, want %d", tt.code, got, tt.exponent)
+		}
+	}
+}
+
+func TestLineGross(t *testing.T) {
+	tests := []struct {
+		name      string
+		qty       int
+		unitPrice Money
+		want      Money
+		wantErr   error
+	}{
+		{name: "product", qty: 3, unitPrice: 1250, want: 3750},
+		{name: "free item", qty: 5, unitPrice: 0, want: 0},
+		{name: "at the limit", qty: 10, unitPrice: maxMoney / 10, want: maxMoney},
+		{name: "over the limit", qty: 11, unitPrice: maxMoney / 10, wantErr: errAmountTooLarge},
+		{name: "would overflow int64", qty: math.MaxInt32, unitPrice: maxMoney, wantErr: errAmountTooLarge},
+		{name: "negative quantity", qty: -1, unitPrice: 100, wantErr: errNegativeAmount},
+		{name: "negative price", qty: 1, unitPrice: -100, wantErr: errNegativeAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LineGross(tt.qty, tt.unitPrice)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LineGross error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LineGross = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		amounts []Money
+		want    Money
+		wantErr error
+	}{
+		{name: "empty", want: 0},
+		{name: "sum", amounts: []Money{100, 250, 5}, want: 355},
+		{name: "at the limit", amounts: []Money{maxMoney - 1, 1}, want: maxMoney},
+		{name: "over the limit", amounts: []Money{maxMoney, 1}, wantErr: errAmountTooLarge},
+		{name: "negative", amounts: []Money{100, -1}, wantErr: errNegativeAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SumMoney(tt.amounts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SumMoney error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SumMoney = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAmounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []OrderLine
+		shipping Money
+		wantErr  error
+	}{
+		{
+			name:     "valid",
+			lines:    []OrderLine{{Qty: 2, UnitPrice: 1000, Discount: 500, Tax: 150}, {Qty: 1, UnitPrice: 300}},
+			shipping: 200,
+		},
+		{
+			name:  "discount equal to gross",
+			lines: []OrderLine{{Qty: 2, UnitPrice: 1000, Discount: 2000}},
+		},
+		{
+			name:    "discount above gross",
+			lines:   []OrderLine{{Qty: 2, UnitPrice: 1000, Discount: 2001}},
+			wantErr: errDiscountExceedsGross,
+		},
+		{
+			name:    "negative discount",
+			lines:   []OrderLine{{Qty: 1, UnitPrice: 1000, Discount: -1}},
+			wantErr: errDiscountExceedsGross,
+		},
+		{
+			name:    "line too large",
+			lines:   []OrderLine{{Qty: 2, UnitPrice: maxMoney}},
+			wantErr: errAmountTooLarge,
+		},
+		{
+			name:    "order too large",
+			lines:   []OrderLine{{Qty: 1, UnitPrice: maxMoney / 2}, {Qty: 1, UnitPrice: maxMoney / 2, Tax: 1}},
+			wantErr: errAmountTooLarge,
+		},
+		{
+			name:     "shipping pushes order over the limit",
+			lines:    []OrderLine{{Qty: 1, UnitPrice: maxMoney}},
+			shipping: 1,
+			wantErr:  errAmountTooLarge,
+		},
+		{
+			name:     "negative shipping",
+			shipping: -1,
+			wantErr:  errNegativeAmount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := Order{Lines: tt.lines}
+			if err := order.CheckAmounts(tt.shipping); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckAmounts error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestComputeTotals(t *testing.T) {
+	order := Order{Lines: []OrderLine{
+		{Qty: 2, UnitPrice: 1000, Discount: 500, Tax: 150},
+		{Qty: 1, UnitPrice: 300, Tax: 30},
+	}}
+	order.ComputeTotals(200)
+
+	if got := []Money{order.Lines[0].Total, order.Lines[1].Total}; !reflect.DeepEqual(got, []Money{1650, 330}) {
+		t.Errorf("line totals = %v, want [1650 330]", got)
+	}
+	want := OrderTotals{Subtotal: 2300, Discount: 500, Shipping: 200, Tax: 180, GrandTotal: 2180}
+	if order.Totals == nil || *order.Totals != want {
+		t.Errorf("totals = %+v, want %+v", order.Totals, want)
+	}
+}
+
+func TestToMajor(t *testing.T) {
+	for _, tt := range []struct {
+		amount   Money
+		currency string
+		want     float64
+	}{
+		{1250, "USD", 12.5},
+		{1500, "JPY", 1500},
+		{1234, "KWD", 1.234},
+		{0, "EUR", 0},
+	} {
+		if got := ToMajor(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("ToMajor(%d, %s) = %v, want %v", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestProratedTotal(t *testing.T) {
+	line := OrderLine{Qty: 3, UnitPrice: 1000, Discount: 100, Tax: 290}
+	line.Total = line.LineTotal()
+
+	tests := []struct {
+		qty  int
+		want Money
+	}{
+		{qty: 0, want: 0},
+		{qty: 1, want: 1063},
+		{qty: 2, want: 2126},
+		{qty: 3, want: 3190},
+		{qty: 5, want: 3190},
+		{qty: -1, want: 0},
+	}
+	for _, tt := range tests {
+		if got := line.ProratedTotal(tt.qty); got != tt.want {
+			t.Errorf("ProratedTotal(%d) = %d, want %d", tt.qty, got, tt.want)
+		}
+	}
+
+	large := OrderLine{Qty: 1e12, Total: maxMoney}
+	if got := large.ProratedTotal(1e12 - 1); got != maxMoney-10 {
+		t.Errorf("ProratedTotal of a large line = %d, want %d", got, maxMoney-10)
+	}
+}
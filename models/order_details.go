@@ -1,5 +1,110 @@
 package models
 
+import "time"
+
+const (
+	OrderStatusOnHold    = "on_hold"
+	OrderStatusNewOrder  = "new_order"
+	OrderStatusConfirmed = "confirmed"
+	// OrderStatusPacked is set when the warehouse reports the order packed.
+	OrderStatusPacked    = "packed"
+	OrderStatusShipped   = "shipped"
+	OrderStatusCancelled = "cancelled"
+	// OrderStatusBackordered means part or all of the order is waiting for stock.
+	OrderStatusBackordered = "backordered"
+	// OrderStatusPartiallyShipped means some but not all of the order has
+	// shipped. Split parents use it when some of their children have.
+	OrderStatusPartiallyShipped = "partially_shipped"
+	OrderStatusDelivered        = "delivered"
+)
+
+// splitProgress orders the statuses of unshipped child orders from least to
+// most progressed.
+var splitProgress = map[string]int{
+	OrderStatusOnHold:      0,
+	OrderStatusBackordered: 1,
+	OrderStatusNewOrder:    2,
+	OrderStatusConfirmed:   3,
+	OrderStatusPacked:      4,
+}
+
+// RollUpStatus derives a split parent order's status from its children's.
+// Cancelled children are ignored unless every child is cancelled. The parent
+// is delivered or shipped once every other child is, and partially_shipped
+// while only some have shipped. Otherwise it takes the status of its least
+// progressed child.
+func RollUpStatus(children []string) string {
+	open := make([]string, 0, len(children))
+	for _, status := range children {
+		if status != OrderStatusCancelled {
+			open = append(open, status)
+		}
+	}
+	if len(open) == 0 {
+		return OrderStatusCancelled
+	}
+
+	shipped, delivered, partial := 0, 0, 0
+	least := OrderStatusPacked
+	for _, status := range open {
+		switch status {
+		case OrderStatusDelivered:
+			delivered++
+			shipped++
+			continue
+		case OrderStatusShipped:
+			shipped++
+			continue
+		case OrderStatusPartiallyShipped:
+			partial++
+			continue
+		}
+		if splitProgress[status] < splitProgress[least] {
+			least = status
+		}
+	}
+	switch {
+	case delivered == len(open):
+		return OrderStatusDelivered
+	case shipped == len(open):
+		return OrderStatusShipped
+	case shipped > 0 || partial > 0:
+		return OrderStatusPartiallyShipped
+	}
+	return least
+}
+
+// ShippingStatus derives the status of an order from its lines once any of
+// it has shipped. ok is false when nothing has shipped yet.
+func (o *Order) ShippingStatus() (status string, ok bool) {
+	open, shipped, delivered, started := 0, 0, 0, false
+	for _, line := range o.Lines {
+		ordered := line.Qty - line.CancelledQty
+		if ordered <= 0 {
+			continue
+		}
+		open++
+		if line.ShippedQty > 0 {
+			started = true
+		}
+		if line.ShippedQty == ordered {
+			shipped++
+		}
+		if line.DeliveredQty == ordered {
+			delivered++
+		}
+	}
+	switch {
+	case !started:
+		return "", false
+	case delivered == open:
+		return OrderStatusDelivered, true
+	case shipped == open:
+		return OrderStatusShipped, true
+	}
+	return OrderStatusPartiallyShipped, true
+}
+
 type Order struct {
 	OrderID      string  `json:"order_id" bson:"order_id"`
 	CustomerName string  `json:"customer_name" bson:"customer_name"`
@@ -9,4 +114,82 @@ type Order struct {
 	Price        float64 `json:"price" bson:"price"`
 	Status       string  `json:"status" bson:"status"`
 	CustomerID   int     `json:"customer_id" bson:"customer_id"`
+	HoldReason   string  `json:"hold_reason,omitempty" bson:"hold_reason,omitempty"`
+	// TenantID is the tenant that owns the order, the same as CustomerID.
+	// Orders stored before it was added do not have it.
+	TenantID int `json:"tenant_id,omitempty" bson:"tenant_id,omitempty"`
+	// Version is incremented by every write to the order. Writes made from
+	// a read of the order only apply if the version is unchanged.
+	Version int `json:"version" bson:"version,omitempty"`
+
+	// Lines holds every SKU of the order. SKUID, Qty and Price mirror the
+	// line of single-line orders for older clients.
+	Lines []OrderLine `json:"lines,omitempty" bson:"lines,omitempty"`
+	// Currency is the ISO 4217 code of the order's amounts. Totals is
+	// unset on orders stored before pricing.
+	Currency         string           `json:"currency,omitempty" bson:"currency,omitempty"`
+	Totals           *OrderTotals     `json:"totals,omitempty" bson:"totals,omitempty"`
+	FulfilmentPolicy FulfilmentPolicy `json:"fulfilment_policy,omitempty" bson:"fulfilment_policy,omitempty"`
+	Reservation      *Reservation     `json:"reservation,omitempty" bson:"reservation,omitempty"`
+
+	// ParentOrderID links a child fulfilment order to the order it was split
+	// from. ChildOrderIDs is set on the parent, which holds no stock itself
+	// and takes its status from its children.
+	ParentOrderID string   `json:"parent_order_id,omitempty" bson:"parent_order_id,omitempty"`
+	ChildOrderIDs []string `json:"child_order_ids,omitempty" bson:"child_order_ids,omitempty"`
+
+	// Where the order ships to and is billed to, and how to reach the
+	// customer. Carriers need them, so events and webhooks carry them.
+	ShippingAddress *Address `json:"shipping_address,omitempty" bson:"shipping_address,omitempty"`
+	BillingAddress  *Address `json:"billing_address,omitempty" bson:"billing_address,omitempty"`
+	CustomerEmail   string   `json:"customer_email,omitempty" bson:"customer_email,omitempty"`
+	CustomerPhone   string   `json:"customer_phone,omitempty" bson:"customer_phone,omitempty"`
+	// Customer is set for orders submitted with a customer_external_id.
+	// CustomerID holds the tenant, as it always has.
+	Customer *OrderCustomer `json:"customer,omitempty" bson:"customer,omitempty"`
+
+	// HubAllocation is set when OMS chose the hub.
+	HubAllocation       *HubAllocation `json:"hub_allocation,omitempty" bson:"hub_allocation,omitempty"`
+	ShippingCoordinates *Coordinates   `json:"shipping_coordinates,omitempty" bson:"shipping_coordinates,omitempty"`
+
+	// CreatedAt is set when the order is first inserted and orders retries
+	// of held orders first in, first out. UpdatedAt is set by every write.
+	CreatedAt   time.Time  `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at" bson:"updated_at,omitempty"`
+	RetryCount  int        `json:"retry_count,omitempty" bson:"retry_count,omitempty"`
+	LastRetryAt *time.Time `json:"last_retry_at,omitempty" bson:"last_retry_at,omitempty"`
+
+	// AmendmentVersion is the number of amendments applied to the order.
+	AmendmentVersion int `json:"amendment_version,omitempty" bson:"amendment_version,omitempty"`
+
+	// Lifecycle timestamps, set when the order first reaches the status.
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty" bson:"confirmed_at,omitempty"`
+	PackedAt    *time.Time `json:"packed_at,omitempty" bson:"packed_at,omitempty"`
+	ShippedAt   *time.Time `json:"shipped_at,omitempty" bson:"shipped_at,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
+
+	// OrderDate is when the customer placed the order and PromiseDate when
+	// it was promised to arrive, as submitted by the tenant.
+	OrderDate   *time.Time `json:"order_date,omitempty" bson:"order_date,omitempty"`
+	PromiseDate *time.Time `json:"promise_date,omitempty" bson:"promise_date,omitempty"`
+
+	// StatusEventAt is the time of the latest warehouse or carrier event
+	// applied to the order. Older events are ignored.
+	StatusEventAt *time.Time `json:"status_event_at,omitempty" bson:"status_event_at,omitempty"`
+}
+
+// EnsureLines fills in Lines for orders stored before multi-line support.
+// Such orders reserved their stock under the order's reservation ID.
+func (o *Order) EnsureLines() {
+	if len(o.Lines) > 0 || o.SKUID == "" {
+		return
+	}
+	line := OrderLine{LineNumber: 1, SKUID: o.SKUID, Qty: o.Qty, Price: o.Price}
+	if o.Reservation != nil && o.Status != OrderStatusOnHold && o.Status != OrderStatusCancelled {
+		line.AllocatedQty = o.Qty
+		line.Allocations = []LineAllocation{{ReservationID: o.Reservation.ID, Qty: o.Qty}}
+	}
+	line.RefreshStatus()
+	o.Lines = []OrderLine{line}
 }
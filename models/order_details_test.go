@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestRollUpStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		children []string
+		want     string
+	}{
+		{name: "all cancelled", children: []string{OrderStatusCancelled, OrderStatusCancelled}, want: OrderStatusCancelled},
+		{name: "no children", children: nil, want: OrderStatusCancelled},
+		{name: "least progressed child", children: []string{OrderStatusPacked, OrderStatusNewOrder, OrderStatusConfirmed}, want: OrderStatusNewOrder},
+		{name: "on hold beats backordered", children: []string{OrderStatusBackordered, OrderStatusOnHold}, want: OrderStatusOnHold},
+		{name: "backordered beats new order", children: []string{OrderStatusNewOrder, OrderStatusBackordered}, want: OrderStatusBackordered},
+		{name: "cancelled child is ignored", children: []string{OrderStatusCancelled, OrderStatusConfirmed}, want: OrderStatusConfirmed},
+		{name: "all shipped", children: []string{OrderStatusShipped, OrderStatusShipped}, want: OrderStatusShipped},
+		{name: "shipped and delivered", children: []string{OrderStatusShipped, OrderStatusDelivered}, want: OrderStatusShipped},
+		{name: "all delivered", children: []string{OrderStatusDelivered, OrderStatusDelivered}, want: OrderStatusDelivered},
+		{name: "delivered but for a cancelled child", children: []string{OrderStatusDelivered, OrderStatusCancelled}, want: OrderStatusDelivered},
+		{name: "some shipped", children: []string{OrderStatusShipped, OrderStatusPacked}, want: OrderStatusPartiallyShipped},
+		{name: "some delivered", children: []string{OrderStatusDelivered, OrderStatusOnHold}, want: OrderStatusPartiallyShipped},
+		{name: "child partially shipped", children: []string{OrderStatusPartiallyShipped, OrderStatusShipped}, want: OrderStatusPartiallyShipped},
+		{name: "single child", children: []string{OrderStatusPacked}, want: OrderStatusPacked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RollUpStatus(tt.children); got != tt.want {
+				t.Errorf("RollUpStatus(%v) = %s, want %s", tt.children, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+// OrderInput is a single order as submitted through a bulk file or the API,
+// before it has been validated. Multi-line orders use Lines; single-line
+// orders may set SKUID, Qty, Price, Discount, Tax and TaxCategory instead.
+// An empty HubID lets OMS allocate the hub.
+type OrderInput struct {
+	TenantID     int     `json:"tenant_id"`
+	OrderID      string  `json:"order_id"`
+	CustomerName string  `json:"customer_name"`
+	HubID        string  `json:"hub_id"`
+	SKUID        string  `json:"sku_id,omitempty"`
+	Qty          int     `json:"quantity,omitempty"`
+	Price        float64 `json:"price,omitempty"`
+	Discount     float64 `json:"discount,omitempty"`
+	Tax          float64 `json:"tax,omitempty"`
+	TaxCategory  string  `json:"tax_category,omitempty"`
+
+	// Currency is the ISO 4217 code of every amount of the order, which are
+	// given in major units. Shipping is the order's shipping charge.
+	Currency string  `json:"currency,omitempty"`
+	Shipping float64 `json:"shipping,omitempty"`
+
+	Lines            []OrderLineInput `json:"lines,omitempty"`
+	FulfilmentPolicy FulfilmentPolicy `json:"fulfilment_policy,omitempty"`
+
+	// ShippingCoordinates is used by the nearest hub allocation strategy.
+	ShippingCoordinates *Coordinates `json:"shipping_coordinates,omitempty"`
+	// AllowSplit overrides the tenant's split_orders setting.
+	AllowSplit *bool `json:"allow_split,omitempty"`
+
+	ShippingAddress *Address `json:"shipping_address,omitempty"`
+	BillingAddress  *Address `json:"billing_address,omitempty"`
+	CustomerEmail   string   `json:"customer_email,omitempty"`
+	CustomerPhone   string   `json:"customer_phone,omitempty"`
+	// CustomerExternalID is the tenant's ID of the customer. Orders with one
+	// are linked to a customer, which is created if it does not exist.
+	CustomerExternalID string `json:"customer_external_id,omitempty"`
+
+	OrderDate   *Date `json:"order_date,omitempty"`
+	PromiseDate *Date `json:"promise_date,omitempty"`
+}
+
+// NormalizedLines returns the order's lines, treating the top-level SKU
+// fields as a single line when Lines is empty.
+func (in OrderInput) NormalizedLines() []OrderLineInput {
+	if len(in.Lines) > 0 {
+		return in.Lines
+	}
+	return []OrderLineInput{{SKUID: in.SKUID, Qty: in.Qty, Price: in.Price, Discount: in.Discount, Tax: in.Tax, TaxCategory: in.TaxCategory}}
+}
@@ -0,0 +1,96 @@
+package models
+
+const (
+	LineStatusPending     = "pending"
+	LineStatusAllocated   = "allocated"
+	LineStatusBackordered = "backordered"
+	LineStatusCancelled   = "cancelled"
+	// Shipping progress, derived from ShippedQty and DeliveredQty.
+	LineStatusPartiallyShipped = "partially_shipped"
+	LineStatusShipped          = "shipped"
+	LineStatusDelivered        = "delivered"
+)
+
+// OrderLine is one SKU of an order. Qty is split into the allocated
+// (reserved) quantity, the backordered quantity waiting for stock and the
+// cancelled quantity. ShippedQty and DeliveredQty track allocated stock
+// through shipments.
+type OrderLine struct {
+	LineNumber int    `json:"line_number" bson:"line_number"`
+	SKUID      string `json:"sku_id" bson:"sku_id"`
+	Qty        int    `json:"qty" bson:"qty"`
+	// TaxCategory is the product tax category tax rules match on.
+	TaxCategory string `json:"tax_category,omitempty" bson:"tax_category,omitempty"`
+	// Price is the unit price in major units, kept for older clients.
+	Price float64 `json:"price" bson:"price"`
+	// UnitPrice, Discount, Tax and Total are in minor units of the order's
+	// currency. Discount and Tax apply to the whole line and Total is
+	// Qty * UnitPrice - Discount + Tax.
+	UnitPrice      Money `json:"unit_price" bson:"unit_price"`
+	Discount       Money `json:"discount,omitempty" bson:"discount,omitempty"`
+	Tax            Money `json:"tax,omitempty" bson:"tax,omitempty"`
+	Total          Money `json:"total" bson:"total"`
+	AllocatedQty   int   `json:"allocated_qty" bson:"allocated_qty"`
+	BackorderedQty int   `json:"backordered_qty" bson:"backordered_qty"`
+	CancelledQty   int   `json:"cancelled_qty" bson:"cancelled_qty"`
+	ShippedQty     int   `json:"shipped_qty" bson:"shipped_qty"`
+	DeliveredQty   int   `json:"delivered_qty" bson:"delivered_qty"`
+	// ReturnedQty is the delivered quantity under a return authorization
+	// that has not been rejected.
+	ReturnedQty int              `json:"returned_qty,omitempty" bson:"returned_qty,omitempty"`
+	Status      string           `json:"status" bson:"status"`
+	Allocations []LineAllocation `json:"allocations,omitempty" bson:"allocations,omitempty"`
+}
+
+// LineAllocation is one inventory reservation made for a line. ShippedQty
+// is the part of it assigned to shipments, which is committed rather than
+// released.
+type LineAllocation struct {
+	ReservationID string `json:"reservation_id" bson:"reservation_id"`
+	Qty           int    `json:"qty" bson:"qty"`
+	ShippedQty    int    `json:"shipped_qty,omitempty" bson:"shipped_qty,omitempty"`
+}
+
+// OpenQty is the quantity neither allocated nor cancelled yet.
+func (l OrderLine) OpenQty() int {
+	return l.Qty - l.AllocatedQty - l.CancelledQty
+}
+
+// ShippableQty is the allocated quantity not shipped yet.
+func (l OrderLine) ShippableQty() int {
+	return l.AllocatedQty - l.ShippedQty
+}
+
+// RefreshStatus derives the line status from its quantities.
+func (l *OrderLine) RefreshStatus() {
+	ordered := l.Qty - l.CancelledQty
+	switch {
+	case ordered > 0 && l.DeliveredQty == ordered:
+		l.Status = LineStatusDelivered
+	case ordered > 0 && l.ShippedQty == ordered:
+		l.Status = LineStatusShipped
+	case l.ShippedQty > 0:
+		l.Status = LineStatusPartiallyShipped
+	case l.BackorderedQty > 0:
+		l.Status = LineStatusBackordered
+	case l.AllocatedQty > 0 && l.OpenQty() == 0:
+		l.Status = LineStatusAllocated
+	case l.CancelledQty == l.Qty:
+		l.Status = LineStatusCancelled
+	default:
+		l.Status = LineStatusPending
+	}
+}
+
+// OrderLineInput is one line of a submitted order. Price is the unit price
+// and Discount and Tax the amounts for the whole line, in major units of the
+// order's currency.
+type OrderLineInput struct {
+	SKUID    string  `json:"sku_id" bson:"sku_id"`
+	Qty      int     `json:"quantity" bson:"quantity"`
+	Price    float64 `json:"price" bson:"price"`
+	Discount float64 `json:"discount,omitempty" bson:"discount,omitempty"`
+	Tax      float64 `json:"tax,omitempty" bson:"tax,omitempty"`
+	// TaxCategory is the product tax category of the SKU.
+	TaxCategory string `json:"tax_category,omitempty" bson:"tax_category,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// Reason codes explain why an order was rejected or held.
+const (
+	ReasonInvalidData        = "INVALID_DATA"
+	ReasonMissingHubOrSKU    = "MISSING_HUB_OR_SKU"
+	ReasonInvalidHub         = "INVALID_HUB"
+	ReasonSKUNotOnHub        = "SKU_NOT_ON_HUB"
+	ReasonInsufficientStock  = "INSUFFICIENT_STOCK_AT_HUB"
+	ReasonDuplicateOrder     = "DUPLICATE_ORDER"
+	ReasonSaveFailed         = "SAVE_FAILED"
+	ReasonIMSUnavailable     = "IMS_UNAVAILABLE"
+	ReasonIMSUnauthorized    = "IMS_UNAUTHORIZED"
+	ReasonReservationExpired = "RESERVATION_EXPIRED"
+	ReasonInventoryFailed    = "INVENTORY_UPDATE_FAILED"
+	ReasonNoHubAvailable     = "NO_HUB_AVAILABLE"
+	ReasonInvalidAddress     = "INVALID_ADDRESS"
+	ReasonInvalidContact     = "INVALID_CONTACT"
+	ReasonInvalidPricing     = "INVALID_PRICING"
+	ReasonTaxUnavailable     = "TAX_UNAVAILABLE"
+	ReasonTenantMismatch     = "TENANT_MISMATCH"
+)
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+const (
+	ReservationStatusReserved  = "reserved"
+	ReservationStatusCommitted = "committed"
+	ReservationStatusReleased  = "released"
+	ReservationStatusExpired   = "expired"
+)
+
+// Reservation is the stock set aside in the inventory service for an order.
+// ExpiresAt is cleared once the order is confirmed.
+type Reservation struct {
+	ID        string     `json:"id" bson:"id"`
+	Status    string     `json:"status" bson:"status"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+}
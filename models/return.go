@@ -0,0 +1,102 @@
+package models
+
+import "time"
+
+// Return authorization statuses. A return moves from requested through
+// approved, received and inspected to refunded, and may be rejected at any
+// point before it is refunded.
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusReceived  = "received"
+	ReturnStatusInspected = "inspected"
+	ReturnStatusRefunded  = "refunded"
+	ReturnStatusRejected  = "rejected"
+)
+
+// Conditions of received return items. Only sellable items are restocked.
+const (
+	ReturnConditionSellable = "sellable"
+	ReturnConditionDamaged  = "damaged"
+)
+
+// ReturnAuthorization (RMA) is a customer's request to send back delivered
+// order lines.
+type ReturnAuthorization struct {
+	RMAID         string       `json:"rma_id" bson:"rma_id"`
+	OrderID       string       `json:"order_id" bson:"order_id"`
+	ParentOrderID string       `json:"parent_order_id,omitempty" bson:"parent_order_id,omitempty"`
+	CustomerID    int          `json:"customer_id" bson:"customer_id"`
+	HubID         string       `json:"hub_id" bson:"hub_id"`
+	Status        string       `json:"status" bson:"status"`
+	Reason        string       `json:"reason" bson:"reason"`
+	Lines         []ReturnLine `json:"lines" bson:"lines"`
+
+	InspectionNotes string   `json:"inspection_notes,omitempty" bson:"inspection_notes,omitempty"`
+	RefundAmount    *float64 `json:"refund_amount,omitempty" bson:"refund_amount,omitempty"`
+	RejectionReason string   `json:"rejection_reason,omitempty" bson:"rejection_reason,omitempty"`
+
+	// RestockPending is true while the sellable quantity of some line has
+	// not been added back to the inventory service.
+	RestockPending bool `json:"restock_pending" bson:"restock_pending"`
+
+	History   []ReturnStatusChange `json:"history" bson:"history"`
+	CreatedAt time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at" bson:"updated_at"`
+}
+
+// ReturnLine is the quantity of an order line being returned.
+type ReturnLine struct {
+	LineNumber   int     `json:"line_number" bson:"line_number"`
+	SKUID        string  `json:"sku_id" bson:"sku_id"`
+	Price        float64 `json:"price" bson:"price"`
+	RequestedQty int     `json:"requested_qty" bson:"requested_qty"`
+	ReceivedQty  int     `json:"received_qty" bson:"received_qty"`
+	SellableQty  int     `json:"sellable_qty" bson:"sellable_qty"`
+	Restocked    bool    `json:"restocked" bson:"restocked"`
+}
+
+// ReturnStatusChange records a step of a return's workflow.
+type ReturnStatusChange struct {
+	Status string    `json:"status" bson:"status"`
+	At     time.Time `json:"at" bson:"at"`
+	Note   string    `json:"note,omitempty" bson:"note,omitempty"`
+}
+
+// ReturnInput is a return requested through the API.
+type ReturnInput struct {
+	Reason string            `json:"reason"`
+	Lines  []ReturnLineInput `json:"lines"`
+}
+
+// ReturnLineInput is the requested quantity of an order line.
+type ReturnLineInput struct {
+	LineNumber int `json:"line_number"`
+	Qty        int `json:"quantity"`
+}
+
+// ReturnReceiptInput records the items that arrived back at the hub.
+type ReturnReceiptInput struct {
+	Lines []ReturnReceiptLine `json:"lines"`
+}
+
+// ReturnReceiptLine is the quantity of a returned line received, split by
+// condition.
+type ReturnReceiptLine struct {
+	LineNumber  int `json:"line_number"`
+	SellableQty int `json:"sellable_quantity"`
+	DamagedQty  int `json:"damaged_quantity"`
+}
+
+// ReturnEvent is emitted as return.<status> and sent to tenant webhooks.
+type ReturnEvent struct {
+	Event         string       `json:"event"`
+	RMAID         string       `json:"rma_id"`
+	OrderID       string       `json:"order_id"`
+	ParentOrderID string       `json:"parent_order_id,omitempty"`
+	CustomerID    int          `json:"customer_id"`
+	Status        string       `json:"status"`
+	Lines         []ReturnLine `json:"lines"`
+	RefundAmount  *float64     `json:"refund_amount,omitempty"`
+	OccurredAt    time.Time    `json:"occurred_at"`
+}
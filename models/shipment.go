@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+const (
+	ShipmentStatusShipped   = "shipped"
+	ShipmentStatusDelivered = "delivered"
+)
+
+// Shipment is a package sent for part or all of an order.
+type Shipment struct {
+	ShipmentID     string             `json:"shipment_id" bson:"shipment_id"`
+	OrderID        string             `json:"order_id" bson:"order_id"`
+	ParentOrderID  string             `json:"parent_order_id,omitempty" bson:"parent_order_id,omitempty"`
+	CustomerID     int                `json:"customer_id" bson:"customer_id"`
+	HubID          string             `json:"hub_id" bson:"hub_id"`
+	Status         string             `json:"status" bson:"status"`
+	Carrier        string             `json:"carrier,omitempty" bson:"carrier,omitempty"`
+	TrackingNumber string             `json:"tracking_number,omitempty" bson:"tracking_number,omitempty"`
+	Package        *PackageDimensions `json:"package,omitempty" bson:"package,omitempty"`
+	Lines          []ShipmentLine     `json:"lines" bson:"lines"`
+
+	// Commits are the inventory reservations consumed by the shipment.
+	// InventoryCommitted turns true once every commit has gone through.
+	Commits            []ShipmentCommit `json:"commits" bson:"commits"`
+	InventoryCommitted bool             `json:"inventory_committed" bson:"inventory_committed"`
+
+	ShippedAt   time.Time  `json:"shipped_at" bson:"shipped_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+}
+
+// ShipmentLine is the quantity of an order line in a shipment.
+type ShipmentLine struct {
+	LineNumber int    `json:"line_number" bson:"line_number"`
+	SKUID      string `json:"sku_id" bson:"sku_id"`
+	Qty        int    `json:"quantity" bson:"quantity"`
+}
+
+// ShipmentCommit is the part of a line allocation committed by a shipment.
+type ShipmentCommit struct {
+	ReservationID string `json:"reservation_id" bson:"reservation_id"`
+	SKUID         string `json:"sku_id" bson:"sku_id"`
+	Qty           int    `json:"quantity" bson:"quantity"`
+	Committed     bool   `json:"committed" bson:"committed"`
+}
+
+// PackageDimensions describes a shipped package.
+type PackageDimensions struct {
+	LengthCm float64 `json:"length_cm" bson:"length_cm"`
+	WidthCm  float64 `json:"width_cm" bson:"width_cm"`
+	HeightCm float64 `json:"height_cm" bson:"height_cm"`
+	WeightKg float64 `json:"weight_kg" bson:"weight_kg"`
+}
+
+// ShipmentInput is a shipment as submitted through the API or a carrier
+// event. ShipmentID and ShippedAt are generated when empty.
+type ShipmentInput struct {
+	ShipmentID     string              `json:"shipment_id,omitempty"`
+	ShippedAt      *time.Time          `json:"shipped_at,omitempty"`
+	Carrier        string              `json:"carrier"`
+	TrackingNumber string              `json:"tracking_number"`
+	Package        *PackageDimensions  `json:"package,omitempty"`
+	Lines          []ShipmentLineInput `json:"lines"`
+}
+
+// ShipmentLineInput is the quantity of an order line to ship.
+type ShipmentLineInput struct {
+	LineNumber int `json:"line_number"`
+	Qty        int `json:"quantity"`
+}
+
+// ShipmentEvent is emitted as order.shipped and order.delivered and sent to
+// tenant webhooks.
+type ShipmentEvent struct {
+	Event          string         `json:"event"`
+	ShipmentID     string         `json:"shipment_id"`
+	OrderID        string         `json:"order_id"`
+	ParentOrderID  string         `json:"parent_order_id,omitempty"`
+	CustomerID     int            `json:"customer_id"`
+	HubID          string         `json:"hub_id"`
+	Carrier        string         `json:"carrier,omitempty"`
+	TrackingNumber string         `json:"tracking_number,omitempty"`
+	Lines          []ShipmentLine `json:"lines"`
+	OrderStatus    string         `json:"order_status"`
+	OccurredAt     time.Time      `json:"occurred_at"`
+
+	ShippingAddress *Address `json:"shipping_address,omitempty"`
+	CustomerEmail   string   `json:"customer_email,omitempty"`
+	CustomerPhone   string   `json:"customer_phone,omitempty"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// TaxProvider decides how the tax of a tenant's orders is worked out.
+type TaxProvider string
+
+const (
+	// TaxProviderSubmitted keeps the tax submitted with each line. It is
+	// the default.
+	TaxProviderSubmitted TaxProvider = "submitted"
+	// TaxProviderRules applies the tenant's tax rules.
+	TaxProviderRules TaxProvider = "rules"
+	// TaxProviderExternal asks the external tax provider.
+	TaxProviderExternal TaxProvider = "external"
+)
+
+func (p TaxProvider) IsValid() bool {
+	switch p {
+	case TaxProviderSubmitted, TaxProviderRules, TaxProviderExternal:
+		return true
+	}
+	return false
+}
+
+// TaxRule is a tax rate of a tenant for orders shipped to a country, and
+// optionally only to one of its regions or for one product tax category.
+// Region matches the state of the shipping address. Rate is a fraction, so
+// 0.05 is 5%.
+type TaxRule struct {
+	CountryCode string  `json:"country_code" bson:"country_code"`
+	Region      string  `json:"region,omitempty" bson:"region,omitempty"`
+	Category    string  `json:"category,omitempty" bson:"category,omitempty"`
+	Rate        float64 `json:"rate" bson:"rate"`
+}
+
+var errInvalidTaxRule = errors.New("tax rules need an ISO 3166-1 alpha-2 country_code and a rate between 0 and 1")
+
+// Validate checks a tax rule for a known country and a rate between 0 and 1.
+func (r TaxRule) Validate() error {
+	if !containsCode(countryCodes, r.CountryCode) || r.Rate < 0 || r.Rate > 1 {
+		return errInvalidTaxRule
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to a line of category shipped
+// to address.
+func (r TaxRule) Matches(address Address, category string) bool {
+	return r.CountryCode == address.CountryCode &&
+		(r.Region == "" || strings.EqualFold(r.Region, address.State)) &&
+		(r.Category == "" || strings.EqualFold(r.Category, category))
+}
+
+// specificity ranks matching rules: a rule for the category beats one for
+// the region, which beats one for the whole country.
+func (r TaxRule) specificity() int {
+	n := 0
+	if r.Category != "" {
+		n += 2
+	}
+	if r.Region != "" {
+		n++
+	}
+	return n
+}
+
+// FindTaxRule returns the most specific rule matching a line of category
+// shipped to address, or false when none does.
+func FindTaxRule(rules []TaxRule, address Address, category string) (TaxRule, bool) {
+	var best TaxRule
+	found := false
+	for _, r := range rules {
+		if r.Matches(address, category) && (!found || r.specificity() > best.specificity()) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,96 @@
+package models
+
+import "testing"
+
+func TestTaxRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    TaxRule
+		wantErr bool
+	}{
+		{name: "country rate", rule: TaxRule{CountryCode: "AE", Rate: 0.05}},
+		{name: "zero rate", rule: TaxRule{CountryCode: "GB", Category: "books", Rate: 0}},
+		{name: "full rate", rule: TaxRule{CountryCode: "US", Region: "CA", Rate: 1}},
+		{name: "unknown country", rule: TaxRule{CountryCode: "XX", Rate: 0.1}, wantErr: true},
+		{name: "missing country", rule: TaxRule{Rate: 0.1}, wantErr: true},
+		{name: "negative rate", rule: TaxRule{CountryCode: "AE", Rate: -0.05}, wantErr: true},
+		{name: "rate given as a percentage", rule: TaxRule{CountryCode: "AE", Rate: 5}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTaxRuleMatches(t *testing.T) {
+	california := Address{State: "CA", CountryCode: "US"}
+
+	tests := []struct {
+		name     string
+		rule     TaxRule
+		address  Address
+		category string
+		want     bool
+	}{
+		{name: "country", rule: TaxRule{CountryCode: "US"}, address: california, category: "books", want: true},
+		{name: "other country", rule: TaxRule{CountryCode: "GB"}, address: california},
+		{name: "region", rule: TaxRule{CountryCode: "US", Region: "CA"}, address: california, want: true},
+		{name: "region ignores case", rule: TaxRule{CountryCode: "US", Region: "ca"}, address: california, want: true},
+		{name: "other region", rule: TaxRule{CountryCode: "US", Region: "NY"}, address: california},
+		{name: "region rule without a state", rule: TaxRule{CountryCode: "US", Region: "CA"}, address: Address{CountryCode: "US"}},
+		{name: "category", rule: TaxRule{CountryCode: "US", Category: "books"}, address: california, category: "books", want: true},
+		{name: "category ignores case", rule: TaxRule{CountryCode: "US", Category: "Books"}, address: california, category: "books", want: true},
+		{name: "other category", rule: TaxRule{CountryCode: "US", Category: "books"}, address: california, category: "food"},
+		{name: "category rule for a line without one", rule: TaxRule{CountryCode: "US", Category: "books"}, address: california},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(tt.address, tt.category); got != tt.want {
+				t.Errorf("Matches(%+v, %q) = %v, want %v", tt.address, tt.category, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindTaxRule(t *testing.T) {
+	rules := []TaxRule{
+		{CountryCode: "US", Rate: 0.01},
+		{CountryCode: "US", Region: "CA", Rate: 0.0725},
+		{CountryCode: "US", Category: "food", Rate: 0},
+		{CountryCode: "US", Region: "CA", Category: "food", Rate: 0.02},
+		{CountryCode: "US", Rate: 0.03},
+		{CountryCode: "GB", Rate: 0.2},
+	}
+
+	tests := []struct {
+		name     string
+		address  Address
+		category string
+		want     float64
+		wantOK   bool
+	}{
+		{name: "country only, first rule wins", address: Address{State: "NY", CountryCode: "US"}, want: 0.01, wantOK: true},
+		{name: "region beats country", address: Address{State: "CA", CountryCode: "US"}, want: 0.0725, wantOK: true},
+		{name: "category beats region", address: Address{State: "NY", CountryCode: "US"}, category: "food", want: 0, wantOK: true},
+		{name: "category and region beat category", address: Address{State: "CA", CountryCode: "US"}, category: "food", want: 0.02, wantOK: true},
+		{name: "no rule for the country", address: Address{CountryCode: "AE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FindTaxRule(rules, tt.address, tt.category)
+			if ok != tt.wantOK || got.Rate != tt.want {
+				t.Errorf("FindTaxRule(%+v, %q) = %+v, %v; want rate %v, %v", tt.address, tt.category, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if _, ok := FindTaxRule(nil, Address{CountryCode: "US"}, ""); ok {
+		t.Error("FindTaxRule with no rules: found a rule")
+	}
+}
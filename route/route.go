@@ -7,8 +7,47 @@ import (
 )
 
 func RegisterRoutes(r *gin.Engine, h *handlers.Handler) {
+	r.GET("/health", h.Health)
+
 	protected := r.Group("/api/orders", middleware.AuthMiddleware())
 	{
+		protected.POST("", h.CreateOrder)
 		protected.POST("/upload", h.UploadCSV)
+		protected.POST("/bulk", h.CreateBulkOrders)
+		protected.GET("/jobs/:job_id", h.GetBulkJob)
+		protected.GET("/jobs/:job_id/rows", h.GetBulkJobRows)
+		protected.GET("/:order_id", h.GetOrder)
+		protected.PATCH("/:order_id", h.AmendOrder)
+		protected.GET("/:order_id/amendments", h.ListAmendments)
+		protected.POST("/:order_id/confirm", h.ConfirmOrder)
+		protected.POST("/:order_id/ship", h.ShipOrder)
+		protected.POST("/:order_id/cancel", h.CancelOrder)
+		protected.POST("/:order_id/shipments", h.CreateShipment)
+		protected.GET("/:order_id/shipments", h.ListShipments)
+		protected.POST("/:order_id/shipments/:shipment_id/deliver", h.DeliverShipment)
+		protected.POST("/:order_id/returns", h.CreateReturn)
+		protected.GET("/:order_id/returns", h.ListReturns)
+	}
+
+	returns := r.Group("/api/returns", middleware.AuthMiddleware())
+	{
+		returns.GET("/:rma_id", h.GetReturn)
+		returns.POST("/:rma_id/approve", h.ApproveReturn)
+		returns.POST("/:rma_id/receive", h.ReceiveReturn)
+		returns.POST("/:rma_id/inspect", h.InspectReturn)
+		returns.POST("/:rma_id/refund", h.RefundReturn)
+		returns.POST("/:rma_id/reject", h.RejectReturn)
+	}
+
+	tenants := r.Group("/api/tenants", middleware.AuthMiddleware())
+	{
+		tenants.GET("/:tenant_id/settings", h.GetTenantSettings)
+		tenants.PUT("/:tenant_id/settings", h.UpdateTenantSettings)
+	}
+
+	customers := r.Group("/api/customers", middleware.AuthMiddleware())
+	{
+		customers.GET("/:customer_id", h.GetCustomer)
+		customers.GET("/:customer_id/orders", h.ListCustomerOrders)
 	}
 }
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/internal/inventory"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/RohitGupta-omniful/OMS/webkooks"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidAmendment = errors.New("an amendment must change something; changed lines need a positive quantity and a non-negative price, new lines a sku_id, a line's discount must not exceed its quantity times price, and at least one line must remain")
+
+const orderUpdatedEvent = "order.updated"
+
+// amendableStatuses are the order statuses that can still be amended. Once
+// an order is confirmed, backordered or shipped it can only be cancelled.
+var amendableStatuses = []string{models.OrderStatusOnHold, models.OrderStatusNewOrder}
+
+// AmendmentValidator checks an amended order against IMS. A non-empty hold
+// reason means the order is acceptable but cannot be fulfilled yet.
+type AmendmentValidator func(ctx context.Context, order models.Order) (holdReason string, err error)
+
+// AmendmentService applies amendments to orders that have not been
+// confirmed, adjusting their stock reservations, and records each amendment
+// as a versioned diff.
+type AmendmentService struct {
+	Reservations *ReservationService
+	Tax          *TaxService
+	Events       EventEmitter
+}
+
+// NewAmendmentService creates an AmendmentService recalculating tax with
+// tax and publishing order.updated through events.
+func NewAmendmentService(reservations *ReservationService, tax *TaxService, events EventEmitter) *AmendmentService {
+	return &AmendmentService{Reservations: reservations, Tax: tax, Events: events}
+}
+
+// Amend applies in to an on_hold or new_order order. On_hold orders are only
+// updated; new_order orders release stock no longer needed and reserve what
+// was added, which may backorder them. Moving a new_order order to another
+// hub releases all of its stock and reserves it again at the new hub. When
+// reserving fails the order is put back on hold for the retry scheduler and
+// the error is returned together with the amended order. version is the
+// order version the caller expects, or AnyVersion.
+func (s *AmendmentService) Amend(ctx context.Context, orderID string, version int, in models.OrderAmendmentInput, validate AmendmentValidator) (*models.Order, *models.OrderAmendment, error) {
+	var (
+		order     *models.Order
+		amendment *models.OrderAmendment
+		releases  []inventory.Reservation
+		err       error
+	)
+	for attempt := 0; attempt < orderUpdateAttempts; attempt++ {
+		order, amendment, releases, err = s.recordAmendment(ctx, orderID, version, in, validate)
+		if !errors.Is(err, errOrderChanged) {
+			break
+		}
+	}
+	if errors.Is(err, errOrderChanged) {
+		return nil, nil, ErrInvalidTransition
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, r := range releases {
+		if rerr := s.Reservations.Inventory.Release(ctx, r); rerr != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to release reservation %s for amended order %s: %v"), r.ID, order.OrderID, rerr)
+		}
+	}
+
+	if order.Status == models.OrderStatusNewOrder && hasOpenQty(order.Lines) {
+		reserved, rerr := s.Reservations.Reserve(ctx, *order)
+		switch {
+		case errors.Is(rerr, ErrVersionConflict):
+			// Another writer moved the order on after the amendment.
+			log.Infof(i18n.Translate(ctx, "order %s changed while reserving amended lines: %v"), order.OrderID, rerr)
+		case rerr != nil:
+			log.Errorf(i18n.Translate(ctx, "failed to reserve stock for amended order %s: %v"), order.OrderID, rerr)
+			if held := s.requeue(ctx, order); held != nil {
+				order = held
+			}
+			s.publish(ctx, order, amendment)
+			return order, amendment, rerr
+		default:
+			order = reserved
+		}
+	}
+
+	s.publish(ctx, order, amendment)
+	return order, amendment, nil
+}
+
+// History lists the amendments of an order, oldest first.
+func (s *AmendmentService) History(ctx context.Context, orderID string) ([]models.OrderAmendment, error) {
+	count, err := db.OrderCollection().CountDocuments(ctx, bson.M{"order_id": orderID})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrOrderNotFound
+	}
+
+	cursor, err := db.OrderAmendmentCollection().Find(ctx, bson.M{"order_id": orderID}, options.Find().SetSort(bson.D{{Key: "version", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	amendments := []models.OrderAmendment{}
+	if err := cursor.All(ctx, &amendments); err != nil {
+		return nil, err
+	}
+	return amendments, nil
+}
+
+// recordAmendment applies an amendment to the order and saves the order and
+// the amendment. It returns the reservations to release for lines that
+// shrank or went away.
+func (s *AmendmentService) recordAmendment(ctx context.Context, orderID string, version int, in models.OrderAmendmentInput, validate AmendmentValidator) (*models.Order, *models.OrderAmendment, []inventory.Reservation, error) {
+	var order models.Order
+	err := db.OrderCollection().FindOne(ctx, bson.M{"order_id": orderID}).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Split orders share line numbers between parent and children, so they
+	// are not amended line by line. On_hold orders whose expired
+	// reservation is still being released keep their allocations until the
+	// sweeper is done.
+	if order.ParentOrderID != "" || len(order.ChildOrderIDs) > 0 || !containsString(amendableStatuses, order.Status) ||
+		(order.Status == models.OrderStatusOnHold && order.Reservation != nil && order.Reservation.Status == models.ReservationStatusReserved) {
+		return nil, nil, nil, ErrInvalidTransition
+	}
+
+	if version != AnyVersion && order.Version != version {
+		return nil, nil, nil, &VersionConflictError{OrderID: orderID, Version: version}
+	}
+
+	fromStatus, fromHub := order.Status, order.HubID
+	order.EnsureLines()
+
+	amended, changes, err := applyAmendment(order, in)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.Tax.Apply(ctx, &amended); err != nil {
+		return nil, nil, nil, err
+	}
+	if order.Totals != nil && amended.Totals.GrandTotal != order.Totals.GrandTotal {
+		changes = append(changes, models.FieldChange{Field: "totals.grand_total", From: order.Totals.GrandTotal, To: amended.Totals.GrandTotal})
+	}
+
+	holdReason, err := validate(ctx, amended)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var releases []inventory.Reservation
+	set := bson.M{}
+	unset := bson.M{}
+	switch {
+	case fromStatus == models.OrderStatusOnHold:
+		if holdReason != order.HoldReason {
+			changes = append(changes, models.FieldChange{Field: "hold_reason", From: order.HoldReason, To: holdReason})
+		}
+		amended.HoldReason = holdReason
+		if holdReason == "" {
+			unset["hold_reason"] = ""
+		} else {
+			set["hold_reason"] = holdReason
+		}
+	case amended.HubID != fromHub:
+		// The stock is held at the old hub; start a new reservation at the
+		// new one.
+		releases = allocatedReservations(order)
+		amended.Lines = unallocatedLines(amended.Lines)
+		amended.Reservation = nil
+		unset["reservation"] = ""
+	default:
+		releases = shrinkAllocations(order, amended.Lines)
+	}
+
+	if amended.HubID != fromHub {
+		amended.HubAllocation = nil
+		unset["hub_allocation"] = ""
+	}
+	amended.AmendmentVersion++
+	amended.SKUID, amended.Qty, amended.Price = "", 0, 0
+	if len(amended.Lines) == 1 {
+		amended.SKUID, amended.Qty, amended.Price = amended.Lines[0].SKUID, amended.Lines[0].Qty, amended.Lines[0].Price
+	}
+	set["hub_id"] = amended.HubID
+	set["lines"] = amended.Lines
+	set["sku_id"], set["qty"], set["price"] = amended.SKUID, amended.Qty, amended.Price
+	set["amendment_version"] = amended.AmendmentVersion
+	if amended.Totals != nil {
+		set["totals"] = amended.Totals
+	}
+
+	amendment := &models.OrderAmendment{
+		AmendmentID: uuid.NewString(),
+		OrderID:     amended.OrderID,
+		CustomerID:  amended.CustomerID,
+		Version:     amended.AmendmentVersion,
+		Changes:     changes,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if _, err := db.OrderAmendmentCollection().InsertOne(ctx, amendment); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, nil, nil, errOrderChanged
+		}
+		return nil, nil, nil, err
+	}
+
+	if err := updateOrderLines(ctx, &amended, set, unset); err != nil {
+		if _, derr := db.OrderAmendmentCollection().DeleteOne(ctx, bson.M{"amendment_id": amendment.AmendmentID}); derr != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to remove amendment %s of order %s: %v"), amendment.AmendmentID, order.OrderID, derr)
+		}
+		return nil, nil, nil, err
+	}
+	return &amended, amendment, releases, nil
+}
+
+// requeue puts a new_order order whose amended lines could not be reserved
+// back on hold and releases its stock, leaving it to the retry scheduler.
+// It returns nil when the order moved on in the meantime.
+func (s *AmendmentService) requeue(ctx context.Context, order *models.Order) *models.Order {
+	held, err := s.Reservations.transition(ctx,
+		bson.M{"order_id": order.OrderID, "status": models.OrderStatusNewOrder}, order.Version,
+		bson.M{"$set": bson.M{"status": models.OrderStatusOnHold, "hold_reason": models.ReasonInventoryFailed}},
+	)
+	if err != nil {
+		log.Errorf(i18n.Translate(ctx, "failed to hold amended order %s: %v"), order.OrderID, err)
+		return nil
+	}
+	if err := s.Reservations.settle(ctx, held, models.ReservationStatusExpired, s.Reservations.Inventory.Release); err != nil {
+		log.Errorf(i18n.Translate(ctx, "failed to release stock of amended order %s: %v"), order.OrderID, err)
+	}
+	return held
+}
+
+func (s *AmendmentService) publish(ctx context.Context, order *models.Order, amendment *models.OrderAmendment) {
+	payload := models.OrderUpdatedEvent{
+		Event:         orderUpdatedEvent,
+		OrderID:       order.OrderID,
+		ParentOrderID: order.ParentOrderID,
+		CustomerID:    order.CustomerID,
+		HubID:         order.HubID,
+		Status:        order.Status,
+		Version:       amendment.Version,
+		Changes:       amendment.Changes,
+		OccurredAt:    amendment.CreatedAt,
+	}
+
+	if s.Events != nil {
+		if err := s.Events.Emit(ctx, orderUpdatedEvent, payload); err != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to emit %s for order %s: %v"), orderUpdatedEvent, order.OrderID, err)
+		}
+	}
+	webkooks.NotifyTenantWebhook(ctx, int64(order.CustomerID), payload)
+}
+
+// applyAmendment returns a copy of order with in applied and the fields it
+// changed. Changed lines lose their backordered and cancelled quantities so
+// they are allocated again from scratch.
+func applyAmendment(order models.Order, in models.OrderAmendmentInput) (models.Order, []models.FieldChange, error) {
+	var changes []models.FieldChange
+
+	if in.HubID != nil && *in.HubID != order.HubID {
+		if *in.HubID == "" {
+			return order, nil, ErrInvalidAmendment
+		}
+		changes = append(changes, models.FieldChange{Field: "hub_id", From: order.HubID, To: *in.HubID})
+		order.HubID = *in.HubID
+	}
+
+	lines := make([]models.OrderLine, len(order.Lines))
+	next := 1
+	for i, line := range order.Lines {
+		line.Allocations = append([]models.LineAllocation(nil), line.Allocations...)
+		lines[i] = line
+		next = max(next, line.LineNumber+1)
+	}
+
+	seen := make(map[int]bool, len(in.Lines))
+	removed := make(map[int]bool)
+	for _, l := range in.Lines {
+		if (l.Qty != nil && *l.Qty <= 0) || (l.Price != nil && *l.Price < 0) {
+			return order, nil, ErrInvalidAmendment
+		}
+
+		if l.LineNumber == 0 {
+			if l.Remove || l.SKUID == "" || l.Qty == nil {
+				return order, nil, ErrInvalidAmendment
+			}
+			line := models.OrderLine{LineNumber: next, SKUID: l.SKUID, Qty: *l.Qty, TaxCategory: l.TaxCategory}
+			var err error
+			if l.Price != nil {
+				if line.UnitPrice, err = models.ToMinor(*l.Price, order.Currency); err != nil {
+					return order, nil, ErrInvalidAmendment
+				}
+				line.Price = *l.Price
+			}
+			if l.Discount != nil {
+				if line.Discount, err = models.ToMinor(*l.Discount, order.Currency); err != nil {
+					return order, nil, ErrInvalidAmendment
+				}
+			}
+			if l.Tax != nil {
+				if line.Tax, err = models.ToMinor(*l.Tax, order.Currency); err != nil {
+					return order, nil, ErrInvalidAmendment
+				}
+			}
+			lines = append(lines, line)
+			changes = append(changes, models.FieldChange{
+				Field: fmt.Sprintf("lines[%d]", next),
+				To: models.OrderLineInput{
+					SKUID:       line.SKUID,
+					Qty:         line.Qty,
+					Price:       line.Price,
+					Discount:    models.ToMajor(line.Discount, order.Currency),
+					Tax:         models.ToMajor(line.Tax, order.Currency),
+					TaxCategory: line.TaxCategory,
+				},
+			})
+			next++
+			continue
+		}
+
+		line := findLine(lines, l.LineNumber)
+		if line == nil || seen[l.LineNumber] || (l.SKUID != "" && l.SKUID != line.SKUID) {
+			return order, nil, ErrInvalidAmendment
+		}
+		seen[l.LineNumber] = true
+
+		if l.Remove {
+			removed[l.LineNumber] = true
+			changes = append(changes, models.FieldChange{
+				Field: fmt.Sprintf("lines[%d]", l.LineNumber),
+				From:  models.OrderLineInput{SKUID: line.SKUID, Qty: line.Qty, Price: line.Price},
+			})
+			continue
+		}
+		if l.Qty != nil && *l.Qty != line.Qty {
+			changes = append(changes, models.FieldChange{Field: fmt.Sprintf("lines[%d].quantity", l.LineNumber), From: line.Qty, To: *l.Qty})
+			line.Qty = *l.Qty
+			line.BackorderedQty = 0
+			line.CancelledQty = 0
+		}
+		if l.Price != nil && *l.Price != line.Price {
+			unitPrice, err := models.ToMinor(*l.Price, order.Currency)
+			if err != nil {
+				return order, nil, ErrInvalidAmendment
+			}
+			changes = append(changes, models.FieldChange{Field: fmt.Sprintf("lines[%d].price", l.LineNumber), From: line.Price, To: *l.Price})
+			line.Price, line.UnitPrice = *l.Price, unitPrice
+		}
+	}
+
+	order.Lines = make([]models.OrderLine, 0, len(lines))
+	for _, line := range lines {
+		if !removed[line.LineNumber] {
+			line.RefreshStatus()
+			order.Lines = append(order.Lines, line)
+		}
+	}
+	if len(order.Lines) == 0 || len(changes) == 0 {
+		return order, nil, ErrInvalidAmendment
+	}
+
+	// Orders stored before pricing have no totals to keep up to date.
+	if order.Totals != nil {
+		if err := order.CheckAmounts(order.Totals.Shipping); err != nil {
+			return order, nil, ErrInvalidAmendment
+		}
+		order.ComputeTotals(order.Totals.Shipping)
+	}
+	return order, changes, nil
+}
+
+// shrinkAllocations gives back the stock of removed lines and the part of a
+// line's allocations above its amended quantity, newest allocation first.
+// Emptied allocations are kept with no quantity so their reservation IDs are
+// not reused.
+func shrinkAllocations(before models.Order, lines []models.OrderLine) []inventory.Reservation {
+	var out []inventory.Reservation
+	for _, old := range before.Lines {
+		line := findLine(lines, old.LineNumber)
+		if line == nil {
+			for _, a := range old.Allocations {
+				if a.Qty > 0 {
+					out = append(out, inventory.Reservation{ID: a.ReservationID, HubID: before.HubID, SKUID: old.SKUID, Qty: a.Qty})
+				}
+			}
+			continue
+		}
+
+		excess := line.AllocatedQty - line.Qty
+		for i := len(line.Allocations) - 1; i >= 0 && excess > 0; i-- {
+			a := &line.Allocations[i]
+			n := min(excess, a.Qty)
+			if n <= 0 {
+				continue
+			}
+			out = append(out, inventory.Reservation{ID: a.ReservationID, HubID: before.HubID, SKUID: line.SKUID, Qty: n})
+			a.Qty -= n
+			line.AllocatedQty -= n
+			excess -= n
+		}
+		line.RefreshStatus()
+	}
+	return out
+}
+
+func hasOpenQty(lines []models.OrderLine) bool {
+	for _, line := range lines {
+		if line.OpenQty() > 0 {
+			return true
+		}
+	}
+	return false
+}
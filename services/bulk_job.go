@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrJobNotFound = errors.New("bulk job not found")
+	// ErrDuplicateJob is returned by CreateJob when the tenant already has an
+	// active job for the same file checksum.
+	ErrDuplicateJob = errors.New("bulk job already exists for this file")
+)
+
+type BulkJobService struct{}
+
+// NewBulkJobService creates and returns a new BulkJobService instance.
+func NewBulkJobService() *BulkJobService {
+	return &BulkJobService{}
+}
+
+// CreateJob stores a new queued job, assigning its ID and timestamps. Unless
+// the job is forced it returns ErrDuplicateJob when the tenant already has a
+// job for the same checksum that has not failed.
+func (s *BulkJobService) CreateJob(ctx context.Context, job *models.BulkJob) error {
+	now := time.Now().UTC()
+	if job.JobID == "" {
+		job.JobID = uuid.NewString()
+	}
+	job.Status = models.BulkJobStatusQueued
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	if !job.Forced {
+		job.ActiveChecksum = job.Checksum
+	}
+
+	_, err := db.BulkJobCollection().InsertOne(ctx, job)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateJob
+	}
+	return err
+}
+
+// GetJob fetches a job by jobID.
+func (s *BulkJobService) GetJob(ctx context.Context, jobID string) (*models.BulkJob, error) {
+	return s.findJob(ctx, bson.M{"job_id": jobID})
+}
+
+// GetTenantJob fetches jobID only if it belongs to tenantID.
+func (s *BulkJobService) GetTenantJob(ctx context.Context, tenantID int, jobID string) (*models.BulkJob, error) {
+	return s.findJob(ctx, bson.M{"tenant_id": tenantID, "job_id": jobID})
+}
+
+func (s *BulkJobService) findJob(ctx context.Context, filter bson.M) (*models.BulkJob, error) {
+	var job models.BulkJob
+	err := db.BulkJobCollection().FindOne(ctx, filter).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindByChecksum returns the latest job that has not failed for the tenant's
+// file checksum, or nil when the file has not been submitted before.
+func (s *BulkJobService) FindByChecksum(ctx context.Context, tenantID int, checksum string) (*models.BulkJob, error) {
+	var job models.BulkJob
+	err := db.BulkJobCollection().FindOne(
+		ctx,
+		bson.M{
+			"tenant_id": tenantID,
+			"checksum":  checksum,
+			"status":    bson.M{"$ne": models.BulkJobStatusFailed},
+		},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// MarkProcessing flags a job as being worked on by the bulk processor and
+// clears any row outcomes after resumeRow left behind by an earlier,
+// interrupted attempt.
+func (s *BulkJobService) MarkProcessing(ctx context.Context, jobID string, resumeRow int) error {
+	if _, err := db.BulkJobRowCollection().DeleteMany(ctx, bson.M{"job_id": jobID, "row_number": bson.M{"$gt": resumeRow}}); err != nil {
+		return err
+	}
+	fields := bson.M{
+		"status":     models.BulkJobStatusProcessing,
+		"updated_at": time.Now().UTC(),
+	}
+	_, err := db.BulkJobCollection().UpdateOne(ctx, bson.M{"job_id": jobID}, bson.M{"$set": fields, "$inc": bson.M{"attempts": 1}})
+	return err
+}
+
+// RequeueJob puts a job back in the queue after a transient failure. Rows up
+// to resumeRow are kept and skipped when the job is picked up again.
+func (s *BulkJobService) RequeueJob(ctx context.Context, jobID string, resumeRow int, reason string) error {
+	return s.setFields(ctx, jobID, bson.M{
+		"status":     models.BulkJobStatusQueued,
+		"resume_row": resumeRow,
+		"error":      reason,
+	})
+}
+
+// CompleteJob records the final counts and rejection report of a job.
+func (s *BulkJobService) CompleteJob(ctx context.Context, jobID string, counts models.BulkJobCounts, reportPath string) error {
+	return s.setFields(ctx, jobID, bson.M{
+		"status":         models.BulkJobStatusCompleted,
+		"created_count":  counts.CreatedCount,
+		"updated_count":  counts.UpdatedCount,
+		"skipped_count":  counts.SkippedCount,
+		"rejected_count": counts.RejectedCount,
+		"report_path":    reportPath,
+		"error":          "",
+	})
+}
+
+// RecordRows appends per-row outcomes to a job's report.
+func (s *BulkJobService) RecordRows(ctx context.Context, rows []models.BulkJobRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		docs = append(docs, row)
+	}
+	_, err := db.BulkJobRowCollection().InsertMany(ctx, docs)
+	return err
+}
+
+// ListRows returns a job's per-row outcomes in file order, optionally
+// filtered to a single outcome.
+func (s *BulkJobService) ListRows(ctx context.Context, jobID string, outcome models.OrderOutcome) ([]models.BulkJobRow, error) {
+	filter := bson.M{"job_id": jobID}
+	if outcome != "" {
+		filter["outcome"] = outcome
+	}
+
+	cursor, err := db.BulkJobRowCollection().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "row_number", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rows := []models.BulkJobRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// FailJob marks a job as failed so the same file can be submitted again.
+func (s *BulkJobService) FailJob(ctx context.Context, jobID string, reason string) error {
+	_, err := db.BulkJobCollection().UpdateOne(ctx, bson.M{"job_id": jobID}, bson.M{
+		"$set": bson.M{
+			"status":     models.BulkJobStatusFailed,
+			"error":      reason,
+			"updated_at": time.Now().UTC(),
+		},
+		"$unset": bson.M{"active_checksum": ""},
+	})
+	return err
+}
+
+func (s *BulkJobService) setFields(ctx context.Context, jobID string, fields bson.M) error {
+	fields["updated_at"] = time.Now().UTC()
+	_, err := db.BulkJobCollection().UpdateOne(ctx, bson.M{"job_id": jobID}, bson.M{"$set": fields})
+	return err
+}
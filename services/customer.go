@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrCustomerNotFound = errors.New("customer not found")
+
+// Page sizes of customer order history.
+const (
+	DefaultCustomerOrdersLimit = 50
+	MaxCustomerOrdersLimit     = 200
+)
+
+type CustomerService struct{}
+
+// NewCustomerService creates and returns a new CustomerService instance.
+func NewCustomerService() *CustomerService {
+	return &CustomerService{}
+}
+
+// Upsert finds the customer with the tenant and external ID of in, creating
+// it if needed, and updates its non-empty contact details and default
+// address from in.
+func (s *CustomerService) Upsert(ctx context.Context, in models.Customer) (*models.Customer, error) {
+	now := time.Now().UTC()
+	set := bson.M{"updated_at": now}
+	if in.Name != "" {
+		set["name"] = in.Name
+	}
+	if in.Email != "" {
+		set["email"] = in.Email
+	}
+	if in.Phone != "" {
+		set["phone"] = in.Phone
+	}
+	if in.DefaultAddress != nil {
+		set["default_address"] = in.DefaultAddress
+	}
+	change := bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{"customer_id": uuid.NewString(), "created_at": now},
+	}
+
+	filter := bson.M{"tenant_id": in.TenantID, "external_id": in.ExternalID}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var customer models.Customer
+	err := db.CustomerCollection().FindOneAndUpdate(ctx, filter, change, opts).Decode(&customer)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent import created the customer first; update it instead.
+		err = db.CustomerCollection().FindOneAndUpdate(ctx, filter, change, opts).Decode(&customer)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// Get returns a customer of the tenant by its OMS customer ID. Customers of
+// other tenants are not found.
+func (s *CustomerService) Get(ctx context.Context, tenantID int, customerID string) (*models.Customer, error) {
+	var customer models.Customer
+	err := db.CustomerCollection().FindOne(ctx, bson.M{"tenant_id": tenantID, "customer_id": customerID}).Decode(&customer)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// ListOrders returns a page of the orders of a customer of the tenant,
+// newest first, optionally only those in status. Split orders are listed
+// once, as their parent.
+func (s *CustomerService) ListOrders(ctx context.Context, tenantID int, customerID, status string, limit, offset int) ([]models.Order, error) {
+	if _, err := s.Get(ctx, tenantID, customerID); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"tenant_id": tenantID, "customer.id": customerID, "parent_order_id": bson.M{"$exists": false}}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "order_id", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+
+	cursor, err := db.OrderCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orders := []models.Order{}
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type EventLogService struct{}
+
+// NewEventLogService creates and returns a new EventLogService instance.
+func NewEventLogService() *EventLogService {
+	return &EventLogService{}
+}
+
+// ClaimEmission records that event is about to be published for the order in
+// the given job. It returns false if the event was already emitted.
+func (s *EventLogService) ClaimEmission(ctx context.Context, tenantID int, orderID string, jobID string, event string) (bool, error) {
+	_, err := db.EmittedEventCollection().InsertOne(ctx, models.EmittedEvent{
+		TenantID:  tenantID,
+		OrderID:   orderID,
+		JobID:     jobID,
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseEmission removes a claim whose event could not be published, so a
+// redelivery can try again.
+func (s *EventLogService) ReleaseEmission(ctx context.Context, tenantID int, orderID string, jobID string, event string) error {
+	_, err := db.EmittedEventCollection().DeleteOne(ctx, bson.M{
+		"tenant_id": tenantID,
+		"order_id":  orderID,
+		"job_id":    jobID,
+		"event":     event,
+	})
+	return err
+}
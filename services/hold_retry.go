@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultHoldRetryInterval = 5 * time.Minute
+	defaultHoldRetryMinAge   = 5 * time.Minute
+	holdRetryBatchSize       = 100
+)
+
+// retryableHoldReasons are the hold reasons that may clear once stock
+// arrives. Orders held for other reasons need manual attention.
+var retryableHoldReasons = []string{
+	models.ReasonInsufficientStock,
+	models.ReasonReservationExpired,
+	models.ReasonInventoryFailed,
+}
+
+// HoldRetryService re-attempts reservations for on_hold and backordered
+// orders, oldest first per hub and SKU, and records every attempt.
+type HoldRetryService struct {
+	Reservations *ReservationService
+	minAge       time.Duration
+
+	// locks serialises retries for the same hub and SKU so the scheduler and
+	// the inventory.updated consumer do not race each other.
+	locks sync.Map
+}
+
+// NewHoldRetryService creates a HoldRetryService using the hold_retry config
+// section.
+func NewHoldRetryService(ctx context.Context, reservations *ReservationService) *HoldRetryService {
+	minAge := config.GetDuration(ctx, "hold_retry.min_age")
+	if minAge <= 0 {
+		minAge = defaultHoldRetryMinAge
+	}
+	return &HoldRetryService{Reservations: reservations, minAge: minAge}
+}
+
+// RetryHubSKU re-attempts held orders for skuID at hubID in creation order and
+// stops at the first order that still cannot be reserved, so later orders do
+// not jump the queue. It returns the number of orders moved to new_order.
+func (s *HoldRetryService) RetryHubSKU(ctx context.Context, hubID, skuID, trigger string) (int, error) {
+	lock, _ := s.locks.LoadOrStore(hubID+":"+skuID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	filter := bson.M{"$and": []bson.M{
+		s.heldFilter(),
+		{"hub_id": hubID, "$or": []bson.M{{"sku_id": skuID}, {"lines.sku_id": skuID}}},
+	}}
+
+	cursor, err := db.OrderCollection().Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(holdRetryBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var orders []models.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return 0, err
+	}
+
+	reserved := 0
+	for _, order := range orders {
+		updated, err := s.Reservations.Reserve(ctx, order)
+		switch {
+		case err == nil && backorderedFor(updated, skuID):
+			s.recordAttempt(ctx, order, skuID, trigger, models.HoldRetryOutcomeBackordered, nil)
+			return reserved, nil
+		case err == nil:
+			reserved++
+			s.recordAttempt(ctx, order, skuID, trigger, models.HoldRetryOutcomeReserved, nil)
+		case errors.Is(err, ErrInvalidTransition), errors.Is(err, ErrVersionConflict):
+			// Another path moved the order on; nothing to do.
+			s.recordAttempt(ctx, order, skuID, trigger, models.HoldRetryOutcomeSkipped, err)
+		default:
+			s.recordAttempt(ctx, order, skuID, trigger, models.HoldRetryOutcomeFailed, err)
+			log.Infof(i18n.Translate(ctx, "held order %s still cannot be reserved at hub %s: %v"), order.OrderID, hubID, err)
+			return reserved, nil
+		}
+	}
+	return reserved, nil
+}
+
+// RetryAll re-attempts held orders for every hub and SKU that has any.
+func (s *HoldRetryService) RetryAll(ctx context.Context, trigger string) (int, error) {
+	cursor, err := db.OrderCollection().Aggregate(ctx, []bson.M{
+		{"$match": s.heldFilter()},
+		{"$unwind": bson.M{"path": "$lines", "preserveNullAndEmptyArrays": true}},
+		{"$group": bson.M{"_id": bson.M{"hub_id": "$hub_id", "sku_id": bson.M{"$ifNull": []string{"$lines.sku_id", "$sku_id"}}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var groups []struct {
+		ID struct {
+			HubID string `bson:"hub_id"`
+			SKUID string `bson:"sku_id"`
+		} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, g := range groups {
+		n, err := s.RetryHubSKU(ctx, g.ID.HubID, g.ID.SKUID, trigger)
+		if err != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to retry held orders for hub %s sku %s: %v"), g.ID.HubID, g.ID.SKUID, err)
+			continue
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// StartScheduler runs RetryAll every hold_retry.interval until ctx is done.
+func (s *HoldRetryService) StartScheduler(ctx context.Context) {
+	interval := config.GetDuration(ctx, "hold_retry.interval")
+	if interval <= 0 {
+		interval = defaultHoldRetryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.RetryAll(ctx, models.HoldRetryTriggerScheduler)
+			if err != nil {
+				log.Errorf(i18n.Translate(ctx, "held order retry run failed: %v"), err)
+				continue
+			}
+			if n > 0 {
+				log.Infof(i18n.Translate(ctx, "moved %d held orders to new_order"), n)
+			}
+		}
+	}
+}
+
+// heldFilter matches orders worth retrying: backordered orders, on_hold
+// orders held for a retryable reason, and on_hold orders with no reason that
+// have waited longer than min_age (their order.created event was lost or
+// never processed). Split parents are excluded; their children are retried
+// instead.
+func (s *HoldRetryService) heldFilter() bson.M {
+	cutoff := time.Now().UTC().Add(-s.minAge)
+	return bson.M{
+		"child_order_ids": notSplitParent,
+		"$or": []bson.M{
+			{"status": models.OrderStatusBackordered},
+			{"status": models.OrderStatusPartiallyShipped, "lines.backordered_qty": bson.M{"$gt": 0}},
+			{"status": models.OrderStatusOnHold, "hold_reason": bson.M{"$in": retryableHoldReasons}},
+			{"status": models.OrderStatusOnHold, "hold_reason": bson.M{"$exists": false}, "created_at": bson.M{"$lte": cutoff}},
+			{"status": models.OrderStatusOnHold, "hold_reason": bson.M{"$exists": false}, "created_at": bson.M{"$exists": false}},
+		},
+	}
+}
+
+// backorderedFor reports whether order is still waiting for stock of skuID.
+func backorderedFor(order *models.Order, skuID string) bool {
+	for _, line := range order.Lines {
+		if line.SKUID == skuID && line.BackorderedQty > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// recordAttempt logs a retry of order for skuID, the SKU being retried rather
+// than the order's first, so multi-line orders are attributed correctly.
+func (s *HoldRetryService) recordAttempt(ctx context.Context, order models.Order, skuID, trigger, outcome string, cause error) {
+	now := time.Now().UTC()
+	attempt := models.HoldRetryAttempt{
+		OrderID:     order.OrderID,
+		HubID:       order.HubID,
+		SKUID:       skuID,
+		Trigger:     trigger,
+		Outcome:     outcome,
+		AttemptedAt: now,
+	}
+	if cause != nil {
+		attempt.Error = cause.Error()
+	}
+
+	if _, err := db.HoldRetryAttemptCollection().InsertOne(ctx, attempt); err != nil {
+		log.Errorf(i18n.Translate(ctx, "failed to record retry attempt for order %s: %v"), order.OrderID, err)
+	}
+	// Bookkeeping only: the counter is incremented atomically and the
+	// attempt may follow a write that already moved the version on.
+	if _, err := db.OrderCollection().UpdateOne(ctx,
+		bson.M{"order_id": order.OrderID},
+		touch(bson.M{"$inc": bson.M{"retry_count": 1}, "$set": bson.M{"last_retry_at": now}}),
+	); err != nil {
+		log.Errorf(i18n.Translate(ctx, "failed to update retry count for order %s: %v"), order.OrderID, err)
+	}
+}
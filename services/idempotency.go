@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type IdempotencyService struct{}
+
+// NewIdempotencyService creates and returns a new IdempotencyService instance.
+func NewIdempotencyService() *IdempotencyService {
+	return &IdempotencyService{}
+}
+
+// Reserve claims key for a request. It returns nil when the key is new, or the
+// existing record when the key has been used before.
+func (s *IdempotencyService) Reserve(ctx context.Context, tenantID int, key string, requestHash string) (*models.IdempotencyRecord, error) {
+	record := models.IdempotencyRecord{
+		Key:         key,
+		TenantID:    tenantID,
+		RequestHash: requestHash,
+		Status:      models.IdempotencyStatusInProgress,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	_, err := db.IdempotencyCollection().InsertOne(ctx, record)
+	if err == nil {
+		return nil, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	var existing models.IdempotencyRecord
+	if err := db.IdempotencyCollection().FindOne(ctx, bson.M{"tenant_id": tenantID, "key": key}).Decode(&existing); err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// Complete records the order created for a reserved key.
+func (s *IdempotencyService) Complete(ctx context.Context, tenantID int, key string, orderID string) error {
+	_, err := db.IdempotencyCollection().UpdateOne(
+		ctx,
+		bson.M{"tenant_id": tenantID, "key": key},
+		bson.M{"$set": bson.M{"status": models.IdempotencyStatusCompleted, "order_id": orderID}},
+	)
+	return err
+}
+
+// Release frees a reserved key so the client can retry after a failed request.
+func (s *IdempotencyService) Release(ctx context.Context, tenantID int, key string) error {
+	_, err := db.IdempotencyCollection().DeleteOne(ctx, bson.M{"tenant_id": tenantID, "key": key})
+	return err
+}
@@ -2,18 +2,47 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/RohitGupta-omniful/OMS/db"
 	"github.com/RohitGupta-omniful/OMS/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrVersionConflict matches every *VersionConflictError.
+	ErrVersionConflict = errors.New("order was changed by another request")
+)
+
+// AnyVersion skips the version check of a write that is guarded by the
+// order's status instead, such as a transition requested without If-Match.
+// The version is still incremented.
+const AnyVersion = -1
+
+// VersionConflictError is returned when a write expected an order version
+// that the order no longer has.
+type VersionConflictError struct {
+	OrderID string
+	Version int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("order %s is no longer at version %d", e.OrderID, e.Version)
+}
+
+func (e *VersionConflictError) Is(target error) bool { return target == ErrVersionConflict }
+
 type OrderService struct{}
 
 type OrderServiceInterface interface {
-	UpdateOrderStatus(ctx context.Context, orderID string, newStatus string) error
-	UpsertOrder(ctx context.Context, order models.Order) error
+	GetOrder(ctx context.Context, orderID string) (*models.Order, error)
+	UpdateOrderStatus(ctx context.Context, orderID string, version int, newStatus string) error
+	SaveOrder(ctx context.Context, order models.Order, policy models.ConflictPolicy) (models.OrderOutcome, error)
 }
 
 // NewOrderService creates and returns a new OrderService instance.
@@ -21,23 +50,235 @@ func NewOrderService() *OrderService {
 	return &OrderService{}
 }
 
-// UpdateOrderStatus updates the status of an order based on orderID.
-func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID string, newStatus string) error {
-	_, err := db.OrderCollection().UpdateOne(
+// GetOrder fetches a single order by orderID.
+func (s *OrderService) GetOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	return s.findOrder(ctx, bson.M{"order_id": orderID})
+}
+
+// GetTenantOrder fetches orderID only if it belongs to tenantID.
+func (s *OrderService) GetTenantOrder(ctx context.Context, tenantID int, orderID string) (*models.Order, error) {
+	return s.findOrder(ctx, bson.M{"customer_id": tenantID, "order_id": orderID})
+}
+
+func (s *OrderService) findOrder(ctx context.Context, filter bson.M) (*models.Order, error) {
+	var order models.Order
+	err := db.OrderCollection().FindOne(ctx, filter).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// UpdateOrderStatus updates the status of an order at the given version.
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID string, version int, newStatus string) error {
+	res, err := db.OrderCollection().UpdateOne(
 		ctx,
-		bson.M{"order_id": orderID},
-		bson.M{"$set": bson.M{"status": newStatus}},
+		withVersion(bson.M{"order_id": orderID}, version),
+		touch(bson.M{"$set": bson.M{"status": newStatus}}),
 	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return missingOrConflict(ctx, orderID, version)
+	}
+	return nil
+}
+
+// DeleteOrder removes a tenant's order. Deleting a missing order is not an
+// error.
+func (s *OrderService) DeleteOrder(ctx context.Context, tenantID int, orderID string) error {
+	_, err := db.OrderCollection().DeleteOne(ctx, bson.M{"customer_id": tenantID, "order_id": orderID})
 	return err
 }
 
-// UpsertOrder inserts or updates an order in the database.
-func (s *OrderService) UpsertOrder(ctx context.Context, order models.Order) error {
-	_, err := db.OrderCollection().UpdateOne(
+// SaveOrder inserts an order or resolves a clash with an existing order of
+// the same tenant and order_id according to policy. An existing order is
+// only ever overwritten while it is still on_hold, so a re-import cannot roll
+// back fulfilment progress. order_id is unique across tenants, so an order_id
+// another tenant already uses is always a duplicate.
+func (s *OrderService) SaveOrder(ctx context.Context, order models.Order, policy models.ConflictPolicy) (models.OrderOutcome, error) {
+	if policy == models.ConflictUpdateOnHold {
+		updated, err := s.replaceHeldOrder(ctx, order)
+		if err != nil {
+			return "", err
+		}
+		if updated {
+			return models.OrderOutcomeUpdated, nil
+		}
+	}
+
+	order.CreatedAt = time.Now().UTC()
+	order.UpdatedAt = order.CreatedAt
+	order.Version = 1
+	res, err := db.OrderCollection().UpdateOne(
 		ctx,
-		bson.M{"order_id": order.OrderID},
-		bson.M{"$set": order},
+		bson.M{"customer_id": order.CustomerID, "order_id": order.OrderID},
+		bson.M{"$setOnInsert": order},
 		options.Update().SetUpsert(true),
 	)
-	return err
+	if mongo.IsDuplicateKeyError(err) {
+		return models.OrderOutcomeDuplicate, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if res.UpsertedCount > 0 {
+		return models.OrderOutcomeCreated, nil
+	}
+
+	if policy == models.ConflictRejectDuplicate {
+		return models.OrderOutcomeDuplicate, nil
+	}
+	return models.OrderOutcomeSkipped, nil
+}
+
+// replaceHeldOrder overwrites an existing on_hold order with order. It
+// reports false when there is no such order.
+func (s *OrderService) replaceHeldOrder(ctx context.Context, order models.Order) (bool, error) {
+	for attempt := 0; attempt < orderUpdateAttempts; attempt++ {
+		var existing models.Order
+		err := db.OrderCollection().FindOne(ctx,
+			bson.M{"customer_id": order.CustomerID, "order_id": order.OrderID, "status": models.OrderStatusOnHold},
+			options.FindOne().SetProjection(bson.M{"version": 1}),
+		).Decode(&existing)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		// Keep the original creation time when replacing a held order.
+		order.CreatedAt = time.Time{}
+		order.UpdatedAt = time.Now().UTC()
+		order.Version = existing.Version + 1
+		update := bson.M{"$set": order}
+		if order.HoldReason == "" {
+			update["$unset"] = bson.M{"hold_reason": ""}
+		}
+		res, err := db.OrderCollection().UpdateOne(
+			ctx,
+			withVersion(bson.M{"customer_id": order.CustomerID, "order_id": order.OrderID, "status": models.OrderStatusOnHold}, existing.Version),
+			update,
+		)
+		if err != nil {
+			return false, err
+		}
+		if res.MatchedCount > 0 {
+			return true, nil
+		}
+	}
+	return false, &VersionConflictError{OrderID: order.OrderID, Version: order.Version - 1}
+}
+
+// OpenOrderCounts returns how many orders each hub still has to ship. Hubs
+// with no open orders are absent from the result.
+func (s *OrderService) OpenOrderCounts(ctx context.Context, hubIDs []string) (map[string]int, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"hub_id": bson.M{"$in": hubIDs},
+			"status": bson.M{"$in": []string{models.OrderStatusNewOrder, models.OrderStatusConfirmed, models.OrderStatusBackordered}},
+		}},
+		{"$group": bson.M{"_id": "$hub_id", "count": bson.M{"$sum": 1}}},
+	}
+
+	cursor, err := db.OrderCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		HubID string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, r := range rows {
+		counts[r.HubID] = r.Count
+	}
+	return counts, nil
+}
+
+// withVersion adds a check for the order version to filter. Orders stored
+// before versioning have no version field and count as version 0.
+func withVersion(filter bson.M, version int) bson.M {
+	switch {
+	case version == AnyVersion:
+	case version == 0:
+		filter["version"] = bson.M{"$exists": false}
+	default:
+		filter["version"] = version
+	}
+	return filter
+}
+
+// touch adds an increment of the order version and the time of the write
+// to update.
+func touch(update bson.M) bson.M {
+	inc, _ := update["$inc"].(bson.M)
+	if inc == nil {
+		inc = bson.M{}
+	}
+	inc["version"] = 1
+	update["$inc"] = inc
+
+	set, _ := update["$set"].(bson.M)
+	if set == nil {
+		set = bson.M{}
+	}
+	if _, ok := set["updated_at"]; !ok {
+		set["updated_at"] = time.Now().UTC()
+	}
+	update["$set"] = set
+	return update
+}
+
+// stampStatus records in set when the order first reached its current
+// status, for the statuses with a lifecycle timestamp.
+func stampStatus(set bson.M, order *models.Order, at time.Time) {
+	var (
+		field string
+		stamp **time.Time
+	)
+	switch order.Status {
+	case models.OrderStatusConfirmed:
+		field, stamp = "confirmed_at", &order.ConfirmedAt
+	case models.OrderStatusPacked:
+		field, stamp = "packed_at", &order.PackedAt
+	case models.OrderStatusShipped:
+		field, stamp = "shipped_at", &order.ShippedAt
+	case models.OrderStatusDelivered:
+		field, stamp = "delivered_at", &order.DeliveredAt
+	case models.OrderStatusCancelled:
+		field, stamp = "cancelled_at", &order.CancelledAt
+	default:
+		return
+	}
+	if *stamp != nil {
+		return
+	}
+	at = at.UTC()
+	*stamp = &at
+	set[field] = at
+}
+
+// missingOrConflict explains why a versioned write of an order matched
+// nothing: the order does not exist, or it is no longer at version.
+func missingOrConflict(ctx context.Context, orderID string, version int) error {
+	count, err := db.OrderCollection().CountDocuments(ctx, bson.M{"order_id": orderID})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrOrderNotFound
+	}
+	return &VersionConflictError{OrderID: orderID, Version: version}
 }
@@ -0,0 +1,574 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/internal/inventory"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/RohitGupta-omniful/OMS/webkooks"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidTransition = errors.New("order is not in a state that allows this change")
+
+// Webhook events for order status changes that are not shipments.
+const (
+	orderStatusChangedEvent = "order.status_changed"
+	orderPackedEvent        = "order.packed"
+)
+
+// notSplitParent matches orders that hold their own stock. Split parents
+// only change status through their children.
+var notSplitParent = bson.M{"$exists": false}
+
+const (
+	defaultReservationTTL = 30 * time.Minute
+	defaultSweepInterval  = time.Minute
+	sweepBatchSize        = 100
+)
+
+// ReservationService reserves stock for accepted orders and settles the
+// reservation when the order is shipped, cancelled or left unconfirmed.
+type ReservationService struct {
+	Inventory inventory.Client
+	ttl       time.Duration
+}
+
+// NewReservationService creates a ReservationService using the reservations
+// config section.
+func NewReservationService(ctx context.Context, client inventory.Client) *ReservationService {
+	ttl := config.GetDuration(ctx, "reservations.ttl")
+	if ttl <= 0 {
+		ttl = defaultReservationTTL
+	}
+	return &ReservationService{Inventory: client, ttl: ttl}
+}
+
+// Reserve allocates stock for an on_hold or backordered order line by line
+// and applies the order's fulfilment policy to any shortfall. A fully
+// allocated order moves to new_order and its reservation expires after the
+// configured TTL unless it is confirmed. An order still waiting for stock is
+// backordered and keeps whatever it has reserved.
+func (s *ReservationService) Reserve(ctx context.Context, order models.Order) (*models.Order, error) {
+	if len(order.ChildOrderIDs) > 0 {
+		return nil, ErrInvalidTransition
+	}
+	order.EnsureLines()
+	fromStatus := order.Status
+
+	res := order.Reservation
+	if res == nil || res.Status != models.ReservationStatusReserved {
+		res = &models.Reservation{ID: uuid.NewString(), Status: models.ReservationStatusReserved}
+	}
+
+	// made holds this attempt's reservations so they can be undone.
+	made := make([]*inventory.Reservation, len(order.Lines))
+	undo := func() {
+		for i, r := range made {
+			if r == nil {
+				continue
+			}
+			if err := s.Inventory.Release(ctx, *r); err != nil {
+				log.Errorf(i18n.Translate(ctx, "failed to release reservation %s for order %s: %v"), r.ID, order.OrderID, err)
+			}
+			made[i] = nil
+		}
+	}
+
+	shortfall := false
+	for i, line := range order.Lines {
+		open := line.OpenQty()
+		if open <= 0 {
+			continue
+		}
+		r := inventory.Reservation{
+			ID:    fmt.Sprintf("%s-%d-%d", res.ID, line.LineNumber, len(line.Allocations)+1),
+			HubID: order.HubID,
+			SKUID: line.SKUID,
+			Qty:   open,
+		}
+		qty, err := s.Inventory.Reserve(ctx, r)
+		if err != nil {
+			undo()
+			return nil, err
+		}
+		if qty < open {
+			shortfall = true
+		}
+		if qty > 0 {
+			r.Qty = qty
+			made[i] = &r
+		}
+	}
+
+	policy := order.FulfilmentPolicy
+	if policy == "" {
+		policy = models.DefaultFulfilmentPolicy
+	}
+	if shortfall && policy == models.FulfilmentShipComplete {
+		undo()
+	}
+
+	allocated, backordered := false, false
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		if open := line.OpenQty(); open > 0 {
+			reserved := 0
+			if made[i] != nil {
+				reserved = made[i].Qty
+				line.AllocatedQty += reserved
+				line.Allocations = append(line.Allocations, models.LineAllocation{ReservationID: made[i].ID, Qty: reserved})
+			}
+			line.BackorderedQty = 0
+			if policy == models.FulfilmentCancelUnavailable {
+				line.CancelledQty += open - reserved
+			} else {
+				line.BackorderedQty = open - reserved
+			}
+		}
+		line.RefreshStatus()
+		allocated = allocated || line.AllocatedQty > 0
+		backordered = backordered || line.BackorderedQty > 0
+	}
+
+	set := bson.M{"lines": order.Lines, "fulfilment_policy": policy}
+	switch {
+	case backordered:
+		order.Status = models.OrderStatusBackordered
+		res.ExpiresAt = nil
+	case allocated:
+		order.Status = models.OrderStatusNewOrder
+		expiresAt := time.Now().UTC().Add(s.ttl)
+		res.ExpiresAt = &expiresAt
+	default:
+		// Nothing was available and the policy cancels unavailable lines.
+		order.Status = models.OrderStatusCancelled
+	}
+	if status, ok := order.ShippingStatus(); ok {
+		// Backordered stock arriving for a partially shipped order.
+		order.Status = status
+		res.ExpiresAt = nil
+	}
+	set["status"] = order.Status
+	now := time.Now().UTC()
+	stampStatus(set, &order, now)
+	set["updated_at"] = now
+	if allocated {
+		order.Reservation = res
+		set["reservation"] = res
+	}
+	order.FulfilmentPolicy = policy
+	order.HoldReason = ""
+
+	result, err := db.OrderCollection().UpdateOne(
+		ctx,
+		withVersion(bson.M{"order_id": order.OrderID, "status": fromStatus}, order.Version),
+		touch(bson.M{"$set": set, "$unset": bson.M{"hold_reason": ""}}),
+	)
+	if err == nil && result.MatchedCount == 0 {
+		err = &VersionConflictError{OrderID: order.OrderID, Version: order.Version}
+	}
+	if err != nil {
+		// The order moved on or could not be updated; give the stock back.
+		undo()
+		return nil, err
+	}
+	order.Version++
+	order.UpdatedAt = now
+	s.rollUp(ctx, &order)
+	return &order, nil
+}
+
+// Hold records why an on_hold order at the given version could not be
+// reserved, which makes it eligible for automatic retries.
+func (s *ReservationService) Hold(ctx context.Context, orderID string, version int, reason string) error {
+	_, err := s.transition(ctx,
+		bson.M{"order_id": orderID, "status": models.OrderStatusOnHold}, version,
+		bson.M{"$set": bson.M{"hold_reason": reason}},
+	)
+	return err
+}
+
+// Confirm accepts a reserved order, which stops its reservation expiring.
+// version is the order version the caller expects, or AnyVersion.
+func (s *ReservationService) Confirm(ctx context.Context, orderID string, version int) (*models.Order, error) {
+	order, err := s.transition(ctx,
+		bson.M{"order_id": orderID, "status": models.OrderStatusNewOrder, "child_order_ids": notSplitParent}, version,
+		bson.M{
+			"$set":   bson.M{"status": models.OrderStatusConfirmed, "confirmed_at": time.Now().UTC()},
+			"$unset": bson.M{"reservation.expires_at": ""},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.rollUp(ctx, order)
+	return order, nil
+}
+
+// Pack records that the warehouse packed a reserved order, which stops its
+// reservation expiring. Events older than the last one applied to the order
+// are rejected with ErrInvalidTransition.
+func (s *ReservationService) Pack(ctx context.Context, orderID string, at time.Time) (*models.Order, error) {
+	at = at.UTC()
+	order, err := s.transition(ctx,
+		bson.M{
+			"order_id":        orderID,
+			"status":          bson.M{"$in": []string{models.OrderStatusNewOrder, models.OrderStatusConfirmed}},
+			"child_order_ids": notSplitParent,
+			"$or": []bson.M{
+				{"status_event_at": bson.M{"$exists": false}},
+				{"status_event_at": bson.M{"$lt": at}},
+			},
+		},
+		AnyVersion,
+		bson.M{
+			"$set":   bson.M{"status": models.OrderStatusPacked, "status_event_at": at, "packed_at": at},
+			"$unset": bson.M{"reservation.expires_at": ""},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.ParentOrderID != "" {
+		s.rollUp(ctx, order)
+	} else {
+		webkooks.NotifyTenantWebhook(ctx, int64(order.CustomerID), models.OrderStatusChangedEvent{
+			Event:           orderPackedEvent,
+			OrderID:         order.OrderID,
+			HubID:           order.HubID,
+			Status:          order.Status,
+			CustomerID:      order.CustomerID,
+			ShippingAddress: order.ShippingAddress,
+			CustomerEmail:   order.CustomerEmail,
+			CustomerPhone:   order.CustomerPhone,
+		})
+	}
+	return order, nil
+}
+
+// cancellableStatuses are the order statuses Cancel accepts.
+var cancellableStatuses = []string{models.OrderStatusOnHold, models.OrderStatusBackordered, models.OrderStatusNewOrder, models.OrderStatusConfirmed, models.OrderStatusPacked}
+
+// Cancel cancels an order that has not shipped and releases its reservation.
+// A failed release is retried by the expiry sweeper. Cancelling a split
+// order cancels every child that has not shipped. version is the order
+// version the caller expects, or AnyVersion.
+func (s *ReservationService) Cancel(ctx context.Context, orderID string, version int) (*models.Order, error) {
+	var parent models.Order
+	err := db.OrderCollection().FindOne(ctx, bson.M{"order_id": orderID, "child_order_ids.0": bson.M{"$exists": true}}).Decode(&parent)
+	if err == nil {
+		return s.cancelSplit(ctx, &parent, version)
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	order, err := s.transition(ctx,
+		bson.M{"order_id": orderID, "status": bson.M{"$in": cancellableStatuses}, "child_order_ids": notSplitParent}, version,
+		bson.M{"$set": bson.M{"status": models.OrderStatusCancelled, "cancelled_at": time.Now().UTC()}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.rollUp(ctx, order)
+	return order, s.settle(ctx, order, models.ReservationStatusReleased, s.Inventory.Release)
+}
+
+// cancelSplit cancels the children of a split order that have not shipped.
+// The parent is never set cancelled directly; it takes its status from its
+// children as each one is rolled up, so it cannot end up cancelled while a
+// child is still live.
+func (s *ReservationService) cancelSplit(ctx context.Context, parent *models.Order, version int) (*models.Order, error) {
+	if !containsString(cancellableStatuses, parent.Status) {
+		return nil, ErrInvalidTransition
+	}
+	if version != AnyVersion && parent.Version != version {
+		return nil, &VersionConflictError{OrderID: parent.OrderID, Version: version}
+	}
+
+	for _, childID := range parent.ChildOrderIDs {
+		if _, err := s.Cancel(ctx, childID, AnyVersion); err != nil && !errors.Is(err, ErrInvalidTransition) {
+			return nil, err
+		}
+	}
+
+	var updated models.Order
+	if err := db.OrderCollection().FindOne(ctx, bson.M{"order_id": parent.OrderID}).Decode(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ExpireReservations releases reservations of orders left unconfirmed past
+// their expiry, putting the orders back on hold, and retries releases for
+// cancelled orders. It returns the number of reservations released.
+func (s *ReservationService) ExpireReservations(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	filter := bson.M{
+		"reservation.status": models.ReservationStatusReserved,
+		"$or": []bson.M{
+			{"status": models.OrderStatusNewOrder, "reservation.expires_at": bson.M{"$lte": now}},
+			{"status": models.OrderStatusCancelled},
+			// Expired earlier but the release failed.
+			{"status": models.OrderStatusOnHold},
+		},
+	}
+
+	cursor, err := db.OrderCollection().Find(ctx, filter, options.Find().SetLimit(sweepBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var orders []models.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, order := range orders {
+		switch order.Status {
+		case models.OrderStatusCancelled:
+			if err := s.settle(ctx, &order, models.ReservationStatusReleased, s.Inventory.Release); err == nil {
+				released++
+			}
+			continue
+		case models.OrderStatusOnHold:
+			if err := s.settle(ctx, &order, models.ReservationStatusExpired, s.Inventory.Release); err == nil {
+				released++
+			}
+			continue
+		}
+
+		// Claim the order first so a concurrent confirm wins over expiry.
+		expired, err := s.transition(ctx,
+			bson.M{"order_id": order.OrderID, "status": models.OrderStatusNewOrder, "reservation.id": order.Reservation.ID, "reservation.expires_at": bson.M{"$lte": now}}, order.Version,
+			bson.M{"$set": bson.M{"status": models.OrderStatusOnHold, "hold_reason": models.ReasonReservationExpired}},
+		)
+		if err != nil {
+			continue
+		}
+		s.rollUp(ctx, expired)
+		if err := s.settle(ctx, expired, models.ReservationStatusExpired, s.Inventory.Release); err == nil {
+			released++
+		}
+	}
+	return released, nil
+}
+
+// StartExpirySweeper runs ExpireReservations every reservations.sweep_interval
+// until ctx is done.
+func (s *ReservationService) StartExpirySweeper(ctx context.Context) {
+	interval := config.GetDuration(ctx, "reservations.sweep_interval")
+	if interval <= 0 {
+		interval = defaultSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ExpireReservations(ctx)
+			if err != nil {
+				log.Errorf(i18n.Translate(ctx, "reservation expiry sweep failed: %v"), err)
+				continue
+			}
+			if n > 0 {
+				log.Infof(i18n.Translate(ctx, "released %d expired or cancelled reservations"), n)
+			}
+		}
+	}
+}
+
+// transition applies update to the order matching filter at version (or
+// AnyVersion), incrementing its version, and returns the updated order.
+// ErrOrderNotFound, a *VersionConflictError or ErrInvalidTransition is
+// returned when nothing matches.
+func (s *ReservationService) transition(ctx context.Context, filter bson.M, version int, update bson.M) (*models.Order, error) {
+	orderID, _ := filter["order_id"].(string)
+	var order models.Order
+	err := db.OrderCollection().FindOneAndUpdate(ctx, withVersion(filter, version), touch(update), options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		var current models.Order
+		err := db.OrderCollection().FindOne(ctx, bson.M{"order_id": orderID}, options.FindOne().SetProjection(bson.M{"version": 1})).Decode(&current)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrOrderNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if version != AnyVersion && current.Version != version {
+			return nil, &VersionConflictError{OrderID: orderID, Version: version}
+		}
+		return nil, ErrInvalidTransition
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// settle calls the inventory service for every allocation of an order's
+// open reservation and records its final status. Orders without an open
+// reservation are left as is.
+func (s *ReservationService) settle(ctx context.Context, order *models.Order, status string, call func(context.Context, inventory.Reservation) error) error {
+	if order.Reservation == nil || order.Reservation.Status != models.ReservationStatusReserved {
+		return nil
+	}
+
+	reservations := allocatedReservations(*order)
+	for _, r := range reservations {
+		if err := call(ctx, r); err != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to settle reservation %s for order %s as %s: %v"), r.ID, order.OrderID, status, err)
+			return err
+		}
+	}
+
+	set := bson.M{"reservation.status": status}
+	if status == models.ReservationStatusExpired {
+		// The stock went back to the pool, so the lines must be allocated
+		// again when the order is retried.
+		order.Lines = unallocatedLines(order.Lines)
+		set["lines"] = order.Lines
+	}
+
+	result, err := db.OrderCollection().UpdateOne(
+		ctx,
+		withVersion(bson.M{"order_id": order.OrderID, "reservation.id": order.Reservation.ID}, order.Version),
+		touch(bson.M{"$set": set}),
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		// The order changed after it was read; the sweeper settles it again.
+		return &VersionConflictError{OrderID: order.OrderID, Version: order.Version}
+	}
+	order.Version++
+	order.Reservation.Status = status
+	return nil
+}
+
+// rollUp recomputes the status of a split order after one of its children
+// changed and notifies the tenant with both order IDs. Failures are logged;
+// the next child change corrects the parent.
+func (s *ReservationService) rollUp(ctx context.Context, child *models.Order) {
+	if child.ParentOrderID == "" {
+		return
+	}
+
+	var parentStatus string
+	for attempt := 0; ; attempt++ {
+		status, err := s.rollUpOnce(ctx, child.ParentOrderID)
+		if err == nil {
+			parentStatus = status
+			break
+		}
+		if !errors.Is(err, ErrVersionConflict) || attempt == orderUpdateAttempts-1 {
+			log.Errorf(i18n.Translate(ctx, "failed to roll up status of order %s: %v"), child.ParentOrderID, err)
+			return
+		}
+	}
+
+	webkooks.NotifyTenantWebhook(ctx, int64(child.CustomerID), models.OrderStatusChangedEvent{
+		Event:         orderStatusChangedEvent,
+		OrderID:       child.OrderID,
+		ParentOrderID: child.ParentOrderID,
+		HubID:         child.HubID,
+		Status:        child.Status,
+		ParentStatus:  parentStatus,
+		CustomerID:    child.CustomerID,
+
+		ShippingAddress: child.ShippingAddress,
+		CustomerEmail:   child.CustomerEmail,
+		CustomerPhone:   child.CustomerPhone,
+	})
+}
+
+// rollUpOnce sets a split order's status from its children's and returns
+// it. The parent is read before its children so a concurrent roll up for
+// another child shows up as a version conflict.
+func (s *ReservationService) rollUpOnce(ctx context.Context, parentID string) (string, error) {
+	var parent models.Order
+	err := db.OrderCollection().FindOne(ctx, bson.M{"order_id": parentID}).Decode(&parent)
+	if err != nil {
+		return "", err
+	}
+
+	cursor, err := db.OrderCollection().Find(ctx, bson.M{"parent_order_id": parentID}, options.Find().SetProjection(bson.M{"status": 1}))
+	if err != nil {
+		return "", err
+	}
+	var children []models.Order
+	if err := cursor.All(ctx, &children); err != nil {
+		return "", err
+	}
+
+	statuses := make([]string, len(children))
+	for i, c := range children {
+		statuses[i] = c.Status
+	}
+	parentStatus := models.RollUpStatus(statuses)
+
+	set := bson.M{"status": parentStatus}
+	if parentStatus != parent.Status {
+		parent.Status = parentStatus
+		stampStatus(set, &parent, time.Now())
+	}
+	result, err := db.OrderCollection().UpdateOne(ctx,
+		withVersion(bson.M{"order_id": parentID}, parent.Version),
+		touch(bson.M{"$set": set}),
+	)
+	if err != nil {
+		return "", err
+	}
+	if result.MatchedCount == 0 {
+		return "", &VersionConflictError{OrderID: parentID, Version: parent.Version}
+	}
+	return parentStatus, nil
+}
+
+func unallocatedLines(lines []models.OrderLine) []models.OrderLine {
+	out := make([]models.OrderLine, len(lines))
+	for i, line := range lines {
+		line.AllocatedQty = 0
+		line.BackorderedQty = 0
+		line.Allocations = nil
+		line.RefreshStatus()
+		out[i] = line
+	}
+	return out
+}
+
+// allocatedReservations lists the inventory reservations held by an order.
+// Orders reserved before multi-line support hold a single reservation under
+// the order's reservation ID.
+func allocatedReservations(order models.Order) []inventory.Reservation {
+	var out []inventory.Reservation
+	for _, line := range order.Lines {
+		for _, a := range line.Allocations {
+			// Shipped stock is committed by its shipment.
+			if qty := a.Qty - a.ShippedQty; qty > 0 {
+				out = append(out, inventory.Reservation{ID: a.ReservationID, HubID: order.HubID, SKUID: line.SKUID, Qty: qty})
+			}
+		}
+	}
+	if len(order.Lines) == 0 && order.SKUID != "" {
+		out = append(out, inventory.Reservation{ID: order.Reservation.ID, HubID: order.HubID, SKUID: order.SKUID, Qty: order.Qty})
+	}
+	return out
+}
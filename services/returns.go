@@ -0,0 +1,487 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/internal/inventory"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/RohitGupta-omniful/OMS/webkooks"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrReturnNotFound          = errors.New("return not found")
+	ErrInvalidReturn           = errors.New("a reason is required and return lines must name delivered order lines with a positive quantity no larger than their delivered quantity not already returned")
+	ErrInvalidReceipt          = errors.New("received lines must belong to the return, with non-negative quantities adding up to no more than the requested quantity")
+	ErrInvalidRefund           = errors.New("refund amount must not be negative")
+	ErrInvalidReturnTransition = errors.New("return is not in a state that allows this change")
+)
+
+const defaultRestockRetryInterval = time.Minute
+
+// ReturnService runs return authorizations (RMAs) through their workflow and
+// restocks sellable returned items.
+type ReturnService struct {
+	Orders    *OrderService
+	Inventory inventory.Client
+	Events    EventEmitter
+}
+
+// NewReturnService creates a ReturnService publishing return events through
+// events.
+func NewReturnService(orders *OrderService, client inventory.Client, events EventEmitter) *ReturnService {
+	return &ReturnService{Orders: orders, Inventory: client, Events: events}
+}
+
+// Create requests a return of delivered order lines. The requested
+// quantities are reserved on the order lines so they cannot be returned
+// twice.
+func (s *ReturnService) Create(ctx context.Context, orderID string, in models.ReturnInput) (*models.ReturnAuthorization, error) {
+	if in.Reason == "" || len(in.Lines) == 0 {
+		return nil, ErrInvalidReturn
+	}
+	for _, l := range in.Lines {
+		if l.Qty <= 0 {
+			return nil, ErrInvalidReturn
+		}
+	}
+
+	var (
+		rma *models.ReturnAuthorization
+		err error
+	)
+	for attempt := 0; attempt < orderUpdateAttempts; attempt++ {
+		rma, err = s.create(ctx, orderID, in)
+		if !errors.Is(err, errOrderChanged) {
+			break
+		}
+	}
+	if errors.Is(err, errOrderChanged) {
+		return nil, ErrInvalidTransition
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, rma)
+	return rma, nil
+}
+
+func (s *ReturnService) create(ctx context.Context, orderID string, in models.ReturnInput) (*models.ReturnAuthorization, error) {
+	order, err := s.Orders.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(order.ChildOrderIDs) > 0 {
+		// Returns are raised against the child that delivered the lines.
+		return nil, ErrInvalidTransition
+	}
+
+	order.EnsureLines()
+
+	now := time.Now().UTC()
+	rma := &models.ReturnAuthorization{
+		RMAID:         uuid.NewString(),
+		OrderID:       order.OrderID,
+		ParentOrderID: order.ParentOrderID,
+		CustomerID:    order.CustomerID,
+		HubID:         order.HubID,
+		Status:        models.ReturnStatusRequested,
+		Reason:        in.Reason,
+		History:       []models.ReturnStatusChange{{Status: models.ReturnStatusRequested, At: now, Note: in.Reason}},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	for _, l := range in.Lines {
+		line := findLine(order.Lines, l.LineNumber)
+		if line == nil || l.Qty > line.DeliveredQty-line.ReturnedQty {
+			return nil, ErrInvalidReturn
+		}
+		line.ReturnedQty += l.Qty
+		rma.Lines = append(rma.Lines, models.ReturnLine{LineNumber: line.LineNumber, SKUID: line.SKUID, Price: line.Price, RequestedQty: l.Qty})
+	}
+
+	if _, err := db.ReturnCollection().InsertOne(ctx, rma); err != nil {
+		return nil, err
+	}
+	if err := updateOrderLines(ctx, order, bson.M{"lines": order.Lines}, nil); err != nil {
+		if _, derr := db.ReturnCollection().DeleteOne(ctx, bson.M{"rma_id": rma.RMAID}); derr != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to remove return %s of order %s: %v"), rma.RMAID, order.OrderID, derr)
+		}
+		return nil, err
+	}
+	return rma, nil
+}
+
+// Approve authorizes a requested return.
+func (s *ReturnService) Approve(ctx context.Context, rmaID string) (*models.ReturnAuthorization, error) {
+	rma, err := s.transition(ctx, rmaID, []string{models.ReturnStatusRequested}, models.ReturnStatusApproved, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, rma)
+	return rma, nil
+}
+
+// Receive records the items that arrived back at the hub and restocks the
+// sellable ones. Requested quantity that did not arrive can be returned
+// again. A failed restock is retried in the background and returned
+// together with the updated return.
+func (s *ReturnService) Receive(ctx context.Context, rmaID string, in models.ReturnReceiptInput) (*models.ReturnAuthorization, error) {
+	rma, err := s.Get(ctx, rmaID)
+	if err != nil {
+		return nil, err
+	}
+	if rma.Status != models.ReturnStatusApproved {
+		return nil, ErrInvalidReturnTransition
+	}
+
+	lines := append([]models.ReturnLine(nil), rma.Lines...)
+	for _, r := range in.Lines {
+		line := findReturnLine(lines, r.LineNumber)
+		if line == nil || r.SellableQty < 0 || r.DamagedQty < 0 || line.ReceivedQty+r.SellableQty+r.DamagedQty > line.RequestedQty {
+			return nil, ErrInvalidReceipt
+		}
+		line.ReceivedQty += r.SellableQty + r.DamagedQty
+		line.SellableQty += r.SellableQty
+	}
+
+	pending := false
+	missing := make(map[int]int)
+	for _, line := range lines {
+		pending = pending || line.SellableQty > 0
+		if n := line.RequestedQty - line.ReceivedQty; n > 0 {
+			missing[line.LineNumber] = n
+		}
+	}
+
+	rma, err = s.transition(ctx, rmaID, []string{models.ReturnStatusApproved}, models.ReturnStatusReceived,
+		bson.M{"lines": lines, "restock_pending": pending}, "")
+	if err != nil {
+		return nil, err
+	}
+	s.releaseReturnedQty(ctx, rma.OrderID, missing)
+	s.publish(ctx, rma)
+	return rma, s.restock(ctx, rma)
+}
+
+// Inspect records the outcome of inspecting received items.
+func (s *ReturnService) Inspect(ctx context.Context, rmaID string, notes string) (*models.ReturnAuthorization, error) {
+	rma, err := s.transition(ctx, rmaID, []string{models.ReturnStatusReceived}, models.ReturnStatusInspected,
+		bson.M{"inspection_notes": notes}, notes)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, rma)
+	return rma, nil
+}
+
+// Refund closes an inspected return. The amount defaults to the sellable
+// received items' share of their order lines' totals, after discount and tax.
+func (s *ReturnService) Refund(ctx context.Context, rmaID string, amount *float64) (*models.ReturnAuthorization, error) {
+	if amount != nil && *amount < 0 {
+		return nil, ErrInvalidRefund
+	}
+	if amount == nil {
+		rma, err := s.Get(ctx, rmaID)
+		if err != nil {
+			return nil, err
+		}
+		total, err := s.defaultRefund(ctx, rma)
+		if err != nil {
+			return nil, err
+		}
+		amount = &total
+	}
+
+	rma, err := s.transition(ctx, rmaID, []string{models.ReturnStatusInspected}, models.ReturnStatusRefunded,
+		bson.M{"refund_amount": *amount}, "")
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, rma)
+	return rma, nil
+}
+
+// defaultRefund prorates the totals of the returned order lines over their
+// sellable received quantity. Lines of orders stored before pricing have no
+// total and are refunded at their price.
+func (s *ReturnService) defaultRefund(ctx context.Context, rma *models.ReturnAuthorization) (float64, error) {
+	order, err := s.Orders.GetOrder(ctx, rma.OrderID)
+	if err != nil {
+		return 0, err
+	}
+	order.EnsureLines()
+
+	var refund models.Money
+	for _, l := range rma.Lines {
+		line := findLine(order.Lines, l.LineNumber)
+		if line == nil {
+			continue
+		}
+		share := line.ProratedTotal(l.SellableQty)
+		if order.Totals == nil {
+			unit, err := models.ToMinor(line.Price, order.Currency)
+			if err != nil {
+				return 0, err
+			}
+			if share, err = models.LineGross(l.SellableQty, unit); err != nil {
+				return 0, err
+			}
+		}
+		if refund, err = models.SumMoney(refund, share); err != nil {
+			return 0, err
+		}
+	}
+	return models.ToMajor(refund, order.Currency), nil
+}
+
+// Reject closes a return without a refund. Quantity rejected before it was
+// received can be returned again.
+func (s *ReturnService) Reject(ctx context.Context, rmaID string, reason string) (*models.ReturnAuthorization, error) {
+	rma, err := s.transition(ctx, rmaID,
+		[]string{models.ReturnStatusRequested, models.ReturnStatusApproved, models.ReturnStatusInspected},
+		models.ReturnStatusRejected, bson.M{"rejection_reason": reason}, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	// Quantity that never arrived was released on receipt.
+	if !returnReceived(rma) {
+		missing := make(map[int]int)
+		for _, line := range rma.Lines {
+			missing[line.LineNumber] = line.RequestedQty
+		}
+		s.releaseReturnedQty(ctx, rma.OrderID, missing)
+	}
+	s.publish(ctx, rma)
+	return rma, nil
+}
+
+// Get returns a return by its RMA ID.
+func (s *ReturnService) Get(ctx context.Context, rmaID string) (*models.ReturnAuthorization, error) {
+	var rma models.ReturnAuthorization
+	err := db.ReturnCollection().FindOne(ctx, bson.M{"rma_id": rmaID}).Decode(&rma)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrReturnNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rma, nil
+}
+
+// List returns an order's returns, oldest first.
+func (s *ReturnService) List(ctx context.Context, orderID string) ([]models.ReturnAuthorization, error) {
+	if _, err := s.Orders.GetOrder(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	cursor, err := db.ReturnCollection().Find(ctx, bson.M{"order_id": orderID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	returns := []models.ReturnAuthorization{}
+	if err := cursor.All(ctx, &returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// RetryRestocks restocks returns whose inventory update failed. It returns
+// the number of returns completed.
+func (s *ReturnService) RetryRestocks(ctx context.Context) (int, error) {
+	cursor, err := db.ReturnCollection().Find(ctx, bson.M{"restock_pending": true}, options.Find().SetLimit(sweepBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var returns []models.ReturnAuthorization
+	if err := cursor.All(ctx, &returns); err != nil {
+		return 0, err
+	}
+
+	done := 0
+	for i := range returns {
+		if err := s.restock(ctx, &returns[i]); err == nil {
+			done++
+		}
+	}
+	return done, nil
+}
+
+// StartRestockRetrier runs RetryRestocks every returns.restock_retry_interval
+// until ctx is done.
+func (s *ReturnService) StartRestockRetrier(ctx context.Context) {
+	interval := config.GetDuration(ctx, "returns.restock_retry_interval")
+	if interval <= 0 {
+		interval = defaultRestockRetryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.RetryRestocks(ctx)
+			if err != nil {
+				log.Errorf(i18n.Translate(ctx, "return restock retry failed: %v"), err)
+				continue
+			}
+			if n > 0 {
+				log.Infof(i18n.Translate(ctx, "restocked %d returns"), n)
+			}
+		}
+	}
+}
+
+// restock adds the sellable quantity of each line back to the hub's stock
+// with an inventory add transaction, recording each line that succeeds. Each
+// add is keyed by return and line, so retrying a line whose add succeeded
+// but was not recorded does not restock it twice.
+func (s *ReturnService) restock(ctx context.Context, rma *models.ReturnAuthorization) error {
+	if !rma.RestockPending {
+		return nil
+	}
+
+	for i := range rma.Lines {
+		line := &rma.Lines[i]
+		if line.Restocked || line.SellableQty == 0 {
+			continue
+		}
+		key := fmt.Sprintf("restock:%s:%d", rma.RMAID, line.LineNumber)
+		if err := s.Inventory.Add(ctx, rma.HubID, line.SKUID, line.SellableQty, key); err != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to restock line %d of return %s: %v"), line.LineNumber, rma.RMAID, err)
+			return err
+		}
+		line.Restocked = true
+		_, err := db.ReturnCollection().UpdateOne(ctx,
+			bson.M{"rma_id": rma.RMAID},
+			bson.M{"$set": bson.M{"lines": rma.Lines}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	rma.RestockPending = false
+	_, err := db.ReturnCollection().UpdateOne(ctx,
+		bson.M{"rma_id": rma.RMAID},
+		bson.M{"$set": bson.M{"restock_pending": false}},
+	)
+	return err
+}
+
+// transition moves a return from one of the from statuses to status,
+// applying set and recording the change in its history.
+func (s *ReturnService) transition(ctx context.Context, rmaID string, from []string, status string, set bson.M, note string) (*models.ReturnAuthorization, error) {
+	now := time.Now().UTC()
+	fields := bson.M{"status": status, "updated_at": now}
+	for k, v := range set {
+		fields[k] = v
+	}
+
+	var rma models.ReturnAuthorization
+	err := db.ReturnCollection().FindOneAndUpdate(ctx,
+		bson.M{"rma_id": rmaID, "status": bson.M{"$in": from}},
+		bson.M{
+			"$set":  fields,
+			"$push": bson.M{"history": models.ReturnStatusChange{Status: status, At: now, Note: note}},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&rma)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, gerr := s.Get(ctx, rmaID); gerr != nil {
+			return nil, gerr
+		}
+		return nil, ErrInvalidReturnTransition
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rma, nil
+}
+
+// releaseReturnedQty makes quantity that was requested but will not come
+// back returnable again. Failures are logged.
+func (s *ReturnService) releaseReturnedQty(ctx context.Context, orderID string, qty map[int]int) {
+	if len(qty) == 0 {
+		return
+	}
+
+	for attempt := 0; attempt < orderUpdateAttempts; attempt++ {
+		order, err := s.Orders.GetOrder(ctx, orderID)
+		if err != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to load order %s to release returned quantity: %v"), orderID, err)
+			return
+		}
+
+		order.EnsureLines()
+		for lineNumber, n := range qty {
+			if line := findLine(order.Lines, lineNumber); line != nil {
+				line.ReturnedQty = max(0, line.ReturnedQty-n)
+			}
+		}
+
+		err = updateOrderLines(ctx, order, bson.M{"lines": order.Lines}, nil)
+		if !errors.Is(err, errOrderChanged) {
+			if err != nil {
+				log.Errorf(i18n.Translate(ctx, "failed to release returned quantity on order %s: %v"), orderID, err)
+			}
+			return
+		}
+	}
+	log.Errorf(i18n.Translate(ctx, "gave up releasing returned quantity on order %s after concurrent changes"), orderID)
+}
+
+func (s *ReturnService) publish(ctx context.Context, rma *models.ReturnAuthorization) {
+	event := "return." + rma.Status
+	payload := models.ReturnEvent{
+		Event:         event,
+		RMAID:         rma.RMAID,
+		OrderID:       rma.OrderID,
+		ParentOrderID: rma.ParentOrderID,
+		CustomerID:    rma.CustomerID,
+		Status:        rma.Status,
+		Lines:         rma.Lines,
+		RefundAmount:  rma.RefundAmount,
+		OccurredAt:    rma.UpdatedAt,
+	}
+
+	if s.Events != nil {
+		if err := s.Events.Emit(ctx, event, payload); err != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to emit %s for return %s: %v"), event, rma.RMAID, err)
+		}
+	}
+	webkooks.NotifyTenantWebhook(ctx, int64(rma.CustomerID), payload)
+}
+
+func returnReceived(rma *models.ReturnAuthorization) bool {
+	for _, change := range rma.History {
+		if change.Status == models.ReturnStatusReceived {
+			return true
+		}
+	}
+	return false
+}
+
+func findReturnLine(lines []models.ReturnLine, lineNumber int) *models.ReturnLine {
+	for i := range lines {
+		if lines[i].LineNumber == lineNumber {
+			return &lines[i]
+		}
+	}
+	return nil
+}
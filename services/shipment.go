@@ -0,0 +1,476 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/internal/inventory"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/RohitGupta-omniful/OMS/webkooks"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/config"
+	"github.com/omniful/go_commons/i18n"
+	"github.com/omniful/go_commons/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrShipmentNotFound = errors.New("shipment not found")
+	ErrShipmentExists   = errors.New("shipment already exists")
+	ErrInvalidShipment  = errors.New("shipment lines must name order lines with a positive quantity no larger than their unshipped allocated quantity")
+)
+
+const (
+	orderShippedEvent   = "order.shipped"
+	orderDeliveredEvent = "order.delivered"
+
+	defaultCommitRetryInterval = time.Minute
+	// commitRetryMinAge leaves new shipments to the request that created them.
+	commitRetryMinAge = time.Minute
+	// orderUpdateAttempts bounds retries of order updates that lost a race
+	// with another change to the same order.
+	orderUpdateAttempts = 3
+)
+
+// EventEmitter publishes order events. *kafka.Producer implements it.
+type EventEmitter interface {
+	Emit(ctx context.Context, key string, event any) error
+}
+
+// shippableStatuses are the order statuses allocated stock can ship from.
+var shippableStatuses = []string{
+	models.OrderStatusNewOrder,
+	models.OrderStatusConfirmed,
+	models.OrderStatusPacked,
+	models.OrderStatusBackordered,
+	models.OrderStatusPartiallyShipped,
+}
+
+// ShipmentService records shipments against orders, commits the shipped
+// stock and derives order and line status from shipped quantities.
+type ShipmentService struct {
+	Reservations *ReservationService
+	Events       EventEmitter
+}
+
+// NewShipmentService creates a ShipmentService publishing order.shipped and
+// order.delivered through events.
+func NewShipmentService(reservations *ReservationService, events EventEmitter) *ShipmentService {
+	return &ShipmentService{Reservations: reservations, Events: events}
+}
+
+// Create ships the given line quantities of an order. The shipped quantity
+// is taken from the lines' allocations, oldest first, and committed with
+// the inventory service. A failed commit is retried in the background and
+// returned together with the recorded shipment and order. version is the
+// order version the caller expects, or AnyVersion.
+func (s *ShipmentService) Create(ctx context.Context, orderID string, version int, in models.ShipmentInput) (*models.Shipment, *models.Order, error) {
+	if len(in.Lines) == 0 {
+		return nil, nil, ErrInvalidShipment
+	}
+	for _, l := range in.Lines {
+		if l.Qty <= 0 {
+			return nil, nil, ErrInvalidShipment
+		}
+	}
+	return s.ship(ctx, orderID, version, in, false)
+}
+
+// ShipRemaining ships everything allocated to an order that has not shipped
+// yet. The lines of in are ignored.
+func (s *ShipmentService) ShipRemaining(ctx context.Context, orderID string, version int, in models.ShipmentInput) (*models.Shipment, *models.Order, error) {
+	in.Lines = nil
+	return s.ship(ctx, orderID, version, in, true)
+}
+
+func (s *ShipmentService) ship(ctx context.Context, orderID string, version int, in models.ShipmentInput, remaining bool) (*models.Shipment, *models.Order, error) {
+	var (
+		shipment *models.Shipment
+		order    *models.Order
+		err      error
+	)
+	for attempt := 0; attempt < orderUpdateAttempts; attempt++ {
+		shipment, order, err = s.recordShipment(ctx, orderID, version, in, remaining)
+		if !errors.Is(err, errOrderChanged) {
+			break
+		}
+	}
+	if errors.Is(err, errOrderChanged) {
+		return nil, nil, ErrInvalidTransition
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.Reservations.rollUp(ctx, order)
+	s.publish(ctx, orderShippedEvent, shipment, order)
+	return shipment, order, s.commit(ctx, shipment)
+}
+
+// errOrderChanged means the order was updated between being read and
+// written.
+var errOrderChanged = errors.New("order changed concurrently")
+
+// recordShipment applies a shipment to the order's lines and saves both.
+func (s *ShipmentService) recordShipment(ctx context.Context, orderID string, version int, in models.ShipmentInput, remaining bool) (*models.Shipment, *models.Order, error) {
+	order, err := s.loadOrder(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version != AnyVersion && order.Version != version {
+		return nil, nil, &VersionConflictError{OrderID: orderID, Version: version}
+	}
+	if len(order.ChildOrderIDs) > 0 || !containsString(shippableStatuses, order.Status) {
+		return nil, nil, ErrInvalidTransition
+	}
+
+	order.EnsureLines()
+
+	if remaining {
+		for _, line := range order.Lines {
+			if qty := line.ShippableQty(); qty > 0 {
+				in.Lines = append(in.Lines, models.ShipmentLineInput{LineNumber: line.LineNumber, Qty: qty})
+			}
+		}
+		if len(in.Lines) == 0 {
+			return nil, nil, ErrInvalidTransition
+		}
+	}
+
+	shippedAt := time.Now().UTC()
+	if in.ShippedAt != nil {
+		shippedAt = in.ShippedAt.UTC()
+	}
+	shipmentID := in.ShipmentID
+	if shipmentID == "" {
+		shipmentID = uuid.NewString()
+	}
+
+	shipment := &models.Shipment{
+		ShipmentID:     shipmentID,
+		OrderID:        order.OrderID,
+		ParentOrderID:  order.ParentOrderID,
+		CustomerID:     order.CustomerID,
+		HubID:          order.HubID,
+		Status:         models.ShipmentStatusShipped,
+		Carrier:        in.Carrier,
+		TrackingNumber: in.TrackingNumber,
+		Package:        in.Package,
+		ShippedAt:      shippedAt,
+	}
+	for _, l := range in.Lines {
+		line := findLine(order.Lines, l.LineNumber)
+		if line == nil || l.Qty > line.ShippableQty() {
+			return nil, nil, ErrInvalidShipment
+		}
+		shipment.Lines = append(shipment.Lines, models.ShipmentLine{LineNumber: line.LineNumber, SKUID: line.SKUID, Qty: l.Qty})
+		shipment.Commits = append(shipment.Commits, takeAllocations(line, l.Qty)...)
+		line.ShippedQty += l.Qty
+		line.RefreshStatus()
+	}
+
+	set := bson.M{"lines": order.Lines}
+	unset := bson.M{"reservation.expires_at": ""}
+	if status, ok := order.ShippingStatus(); ok {
+		order.Status = status
+		set["status"] = status
+		stampStatus(set, order, shippedAt)
+	}
+	if order.Status == models.OrderStatusShipped && order.Reservation != nil {
+		order.Reservation.Status = models.ReservationStatusCommitted
+		order.Reservation.ExpiresAt = nil
+		set["reservation.status"] = models.ReservationStatusCommitted
+	}
+
+	if _, err := db.ShipmentCollection().InsertOne(ctx, shipment); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, nil, ErrShipmentExists
+		}
+		return nil, nil, err
+	}
+	if err := updateOrderLines(ctx, order, set, unset); err != nil {
+		if _, derr := db.ShipmentCollection().DeleteOne(ctx, bson.M{"shipment_id": shipment.ShipmentID}); derr != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to remove shipment %s of order %s: %v"), shipment.ShipmentID, order.OrderID, derr)
+		}
+		return nil, nil, err
+	}
+	return shipment, order, nil
+}
+
+// Deliver marks a shipment delivered at the given time and adds its
+// quantities to the order lines' delivered quantities. Delivery times before
+// the shipment shipped are rejected as out of order.
+func (s *ShipmentService) Deliver(ctx context.Context, orderID, shipmentID string, at time.Time) (*models.Shipment, *models.Order, error) {
+	at = at.UTC()
+	var shipment models.Shipment
+	err := db.ShipmentCollection().FindOneAndUpdate(ctx,
+		bson.M{"shipment_id": shipmentID, "order_id": orderID, "status": models.ShipmentStatusShipped, "shipped_at": bson.M{"$lte": at}},
+		bson.M{"$set": bson.M{"status": models.ShipmentStatusDelivered, "delivered_at": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&shipment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		count, cerr := db.ShipmentCollection().CountDocuments(ctx, bson.M{"shipment_id": shipmentID, "order_id": orderID})
+		if cerr != nil {
+			return nil, nil, cerr
+		}
+		if count == 0 {
+			return nil, nil, ErrShipmentNotFound
+		}
+		return nil, nil, ErrInvalidTransition
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var order *models.Order
+	for attempt := 0; attempt < orderUpdateAttempts; attempt++ {
+		order, err = s.recordDelivery(ctx, &shipment)
+		if !errors.Is(err, errOrderChanged) {
+			break
+		}
+	}
+	if err != nil {
+		log.Errorf(i18n.Translate(ctx, "failed to record delivery of shipment %s on order %s: %v"), shipmentID, orderID, err)
+		// Put the shipment back to shipped so the delivery can be retried
+		// and is not counted twice.
+		_, uerr := db.ShipmentCollection().UpdateOne(ctx,
+			bson.M{"shipment_id": shipmentID, "status": models.ShipmentStatusDelivered},
+			bson.M{"$set": bson.M{"status": models.ShipmentStatusShipped}, "$unset": bson.M{"delivered_at": ""}},
+		)
+		if uerr != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to revert delivery of shipment %s on order %s: %v"), shipmentID, orderID, uerr)
+		}
+		return nil, nil, err
+	}
+
+	s.Reservations.rollUp(ctx, order)
+	s.publish(ctx, orderDeliveredEvent, &shipment, order)
+	return &shipment, order, nil
+}
+
+func (s *ShipmentService) recordDelivery(ctx context.Context, shipment *models.Shipment) (*models.Order, error) {
+	order, err := s.loadOrder(ctx, shipment.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	order.EnsureLines()
+	for _, l := range shipment.Lines {
+		if line := findLine(order.Lines, l.LineNumber); line != nil {
+			line.DeliveredQty += l.Qty
+			line.RefreshStatus()
+		}
+	}
+
+	set := bson.M{"lines": order.Lines}
+	if status, ok := order.ShippingStatus(); ok {
+		order.Status = status
+		set["status"] = status
+		stampStatus(set, order, *shipment.DeliveredAt)
+	}
+	if err := updateOrderLines(ctx, order, set, nil); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// List returns an order's shipments, oldest first.
+func (s *ShipmentService) List(ctx context.Context, orderID string) ([]models.Shipment, error) {
+	if _, err := s.loadOrder(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	cursor, err := db.ShipmentCollection().Find(ctx, bson.M{"order_id": orderID}, options.Find().SetSort(bson.D{{Key: "shipped_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	shipments := []models.Shipment{}
+	if err := cursor.All(ctx, &shipments); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// RetryCommits commits the stock of shipments whose inventory commit failed.
+// It returns the number of shipments completed.
+func (s *ShipmentService) RetryCommits(ctx context.Context) (int, error) {
+	filter := bson.M{"inventory_committed": false, "shipped_at": bson.M{"$lte": time.Now().UTC().Add(-commitRetryMinAge)}}
+	cursor, err := db.ShipmentCollection().Find(ctx, filter, options.Find().SetLimit(sweepBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var shipments []models.Shipment
+	if err := cursor.All(ctx, &shipments); err != nil {
+		return 0, err
+	}
+
+	done := 0
+	for i := range shipments {
+		if err := s.commit(ctx, &shipments[i]); err == nil {
+			done++
+		}
+	}
+	return done, nil
+}
+
+// StartCommitRetrier runs RetryCommits every shipments.commit_retry_interval
+// until ctx is done.
+func (s *ShipmentService) StartCommitRetrier(ctx context.Context) {
+	interval := config.GetDuration(ctx, "shipments.commit_retry_interval")
+	if interval <= 0 {
+		interval = defaultCommitRetryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.RetryCommits(ctx)
+			if err != nil {
+				log.Errorf(i18n.Translate(ctx, "shipment commit retry failed: %v"), err)
+				continue
+			}
+			if n > 0 {
+				log.Infof(i18n.Translate(ctx, "committed stock for %d shipments"), n)
+			}
+		}
+	}
+}
+
+// commit sends the shipment's outstanding commits to the inventory service,
+// recording each one that succeeds.
+func (s *ShipmentService) commit(ctx context.Context, shipment *models.Shipment) error {
+	for i := range shipment.Commits {
+		c := &shipment.Commits[i]
+		if c.Committed {
+			continue
+		}
+		r := inventory.Reservation{ID: c.ReservationID, HubID: shipment.HubID, SKUID: c.SKUID, Qty: c.Qty}
+		if err := s.Reservations.Inventory.Commit(ctx, r); err != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to commit reservation %s for shipment %s: %v"), c.ReservationID, shipment.ShipmentID, err)
+			return err
+		}
+		c.Committed = true
+		_, err := db.ShipmentCollection().UpdateOne(ctx,
+			bson.M{"shipment_id": shipment.ShipmentID},
+			bson.M{"$set": bson.M{"commits": shipment.Commits}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	shipment.InventoryCommitted = true
+	_, err := db.ShipmentCollection().UpdateOne(ctx,
+		bson.M{"shipment_id": shipment.ShipmentID},
+		bson.M{"$set": bson.M{"inventory_committed": true}},
+	)
+	return err
+}
+
+func (s *ShipmentService) publish(ctx context.Context, event string, shipment *models.Shipment, order *models.Order) {
+	payload := models.ShipmentEvent{
+		Event:          event,
+		ShipmentID:     shipment.ShipmentID,
+		OrderID:        shipment.OrderID,
+		ParentOrderID:  shipment.ParentOrderID,
+		CustomerID:     shipment.CustomerID,
+		HubID:          shipment.HubID,
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		Lines:          shipment.Lines,
+		OrderStatus:    order.Status,
+		OccurredAt:     time.Now().UTC(),
+
+		ShippingAddress: order.ShippingAddress,
+		CustomerEmail:   order.CustomerEmail,
+		CustomerPhone:   order.CustomerPhone,
+	}
+
+	if s.Events != nil {
+		if err := s.Events.Emit(ctx, event, payload); err != nil {
+			log.Errorf(i18n.Translate(ctx, "failed to emit %s for order %s: %v"), event, shipment.OrderID, err)
+		}
+	}
+	webkooks.NotifyTenantWebhook(ctx, int64(shipment.CustomerID), payload)
+}
+
+func (s *ShipmentService) loadOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	var order models.Order
+	err := db.OrderCollection().FindOne(ctx, bson.M{"order_id": orderID}).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// updateOrderLines saves changes to an order provided it is still at the
+// version that was read, and increments the version. errOrderChanged is
+// returned otherwise.
+func updateOrderLines(ctx context.Context, order *models.Order, set, unset bson.M) error {
+	now := time.Now().UTC()
+	set["updated_at"] = now
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	result, err := db.OrderCollection().UpdateOne(ctx, withVersion(bson.M{"order_id": order.OrderID}, order.Version), touch(update))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errOrderChanged
+	}
+	order.Version++
+	order.UpdatedAt = now
+	return nil
+}
+
+// takeAllocations assigns qty units of a line to a shipment from its
+// allocations, oldest first.
+func takeAllocations(line *models.OrderLine, qty int) []models.ShipmentCommit {
+	var commits []models.ShipmentCommit
+	for i := range line.Allocations {
+		a := &line.Allocations[i]
+		n := min(qty, a.Qty-a.ShippedQty)
+		if n <= 0 {
+			continue
+		}
+		a.ShippedQty += n
+		qty -= n
+		commits = append(commits, models.ShipmentCommit{ReservationID: a.ReservationID, SKUID: line.SKUID, Qty: n})
+		if qty == 0 {
+			break
+		}
+	}
+	return commits
+}
+
+func findLine(lines []models.OrderLine, lineNumber int) *models.OrderLine {
+	for i := range lines {
+		if lines[i].LineNumber == lineNumber {
+			return &lines[i]
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
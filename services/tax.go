@@ -0,0 +1,209 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/models"
+	"github.com/omniful/go_commons/config"
+)
+
+// ErrTaxUnavailable is returned when the tax of an order could not be
+// worked out, e.g. because the external tax provider failed.
+var ErrTaxUnavailable = errors.New("tax could not be calculated, try again later")
+
+const defaultTaxTimeout = 5 * time.Second
+
+// TaxRequest is an order to work out tax for. Line amounts are in minor
+// units of Currency, after discount.
+type TaxRequest struct {
+	TenantID        int              `json:"tenant_id"`
+	OrderID         string           `json:"order_id"`
+	Currency        string           `json:"currency"`
+	ShippingAddress *models.Address  `json:"shipping_address,omitempty"`
+	Lines           []TaxRequestLine `json:"lines"`
+}
+
+// TaxRequestLine is one line of a TaxRequest.
+type TaxRequestLine struct {
+	LineNumber  int          `json:"line_number"`
+	SKUID       string       `json:"sku_id"`
+	TaxCategory string       `json:"tax_category,omitempty"`
+	Qty         int          `json:"quantity"`
+	Amount      models.Money `json:"amount"`
+}
+
+// TaxCalculator works out the tax of the lines of an order. It returns the
+// tax of every line, in minor units, by line number.
+type TaxCalculator interface {
+	Calculate(ctx context.Context, req TaxRequest) (map[int]models.Money, error)
+}
+
+// RuleTaxCalculator applies a tenant's tax rules to the line amounts. Lines
+// no rule matches, and orders without a shipping address, are not taxed.
+type RuleTaxCalculator struct {
+	Rules []models.TaxRule
+}
+
+// Calculate implements TaxCalculator.
+func (c RuleTaxCalculator) Calculate(ctx context.Context, req TaxRequest) (map[int]models.Money, error) {
+	taxes := make(map[int]models.Money, len(req.Lines))
+	for _, line := range req.Lines {
+		taxes[line.LineNumber] = 0
+		if req.ShippingAddress == nil {
+			continue
+		}
+		if rule, ok := models.FindTaxRule(c.Rules, *req.ShippingAddress, line.TaxCategory); ok {
+			taxes[line.LineNumber] = models.Money(math.Round(float64(line.Amount) * rule.Rate))
+		}
+	}
+	return taxes, nil
+}
+
+// HTTPTaxCalculator asks an external tax provider. The provider is sent the
+// TaxRequest as JSON and answers with the tax of every line:
+//
+//	{"lines": [{"line_number": 1, "tax": 945}]}
+type HTTPTaxCalculator struct {
+	URL        string
+	AuthToken  string
+	HTTPClient *http.Client
+}
+
+type taxResponse struct {
+	Lines []struct {
+		LineNumber int          `json:"line_number"`
+		Tax        models.Money `json:"tax"`
+	} `json:"lines"`
+}
+
+// Calculate implements TaxCalculator.
+func (c *HTTPTaxCalculator) Calculate(ctx context.Context, req TaxRequest) (map[int]models.Money, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.AuthToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.AuthToken)
+	}
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("tax provider returned status %d: %s", resp.StatusCode, respBody)
+	}
+
+	var result taxResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	taxes := make(map[int]models.Money, len(result.Lines))
+	for _, line := range result.Lines {
+		if line.Tax < 0 {
+			return nil, fmt.Errorf("tax provider returned negative tax for line %d", line.LineNumber)
+		}
+		taxes[line.LineNumber] = line.Tax
+	}
+	return taxes, nil
+}
+
+// TaxSettingsGetter looks up the settings holding a tenant's tax provider
+// and rules. TenantSettingsService implements it.
+type TaxSettingsGetter interface {
+	Get(ctx context.Context, tenantID int) (*models.TenantSettings, error)
+}
+
+// TaxService works out order tax with the calculator each tenant chose.
+type TaxService struct {
+	Settings TaxSettingsGetter
+	// External is the calculator of tenants using the external provider,
+	// nil when no provider is configured.
+	External TaxCalculator
+}
+
+// NewTaxService creates a TaxService, using the external tax provider at
+// tax.provider_url when it is set.
+func NewTaxService(ctx context.Context) *TaxService {
+	s := &TaxService{Settings: NewTenantSettingsService()}
+	if url := config.GetString(ctx, "tax.provider_url"); url != "" {
+		timeout := config.GetDuration(ctx, "tax.timeout")
+		if timeout <= 0 {
+			timeout = defaultTaxTimeout
+		}
+		s.External = &HTTPTaxCalculator{
+			URL:        url,
+			AuthToken:  config.GetString(ctx, "tax.auth_token"),
+			HTTPClient: &http.Client{Timeout: timeout},
+		}
+	}
+	return s
+}
+
+// Apply works out the tax of every line of order and updates its totals.
+// Orders of tenants keeping the submitted tax, and orders stored before
+// pricing, are left unchanged.
+func (s *TaxService) Apply(ctx context.Context, order *models.Order) error {
+	if order.Totals == nil {
+		return nil
+	}
+	settings, err := s.Settings.Get(ctx, order.CustomerID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTaxUnavailable, err)
+	}
+
+	var calculator TaxCalculator
+	switch {
+	case settings == nil || settings.TaxProvider == "" || settings.TaxProvider == models.TaxProviderSubmitted:
+		return nil
+	case settings.TaxProvider == models.TaxProviderRules:
+		calculator = RuleTaxCalculator{Rules: settings.TaxRules}
+	case s.External == nil:
+		return fmt.Errorf("%w: no external tax provider is configured", ErrTaxUnavailable)
+	default:
+		calculator = s.External
+	}
+
+	req := TaxRequest{TenantID: order.CustomerID, OrderID: order.OrderID, Currency: order.Currency, ShippingAddress: order.ShippingAddress}
+	for _, line := range order.Lines {
+		req.Lines = append(req.Lines, TaxRequestLine{
+			LineNumber:  line.LineNumber,
+			SKUID:       line.SKUID,
+			TaxCategory: line.TaxCategory,
+			Qty:         line.Qty,
+			Amount:      models.Money(line.Qty)*line.UnitPrice - line.Discount,
+		})
+	}
+
+	taxes, err := calculator.Calculate(ctx, req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTaxUnavailable, err)
+	}
+	for i := range order.Lines {
+		tax, ok := taxes[order.Lines[i].LineNumber]
+		if !ok {
+			return fmt.Errorf("%w: no tax for line %d", ErrTaxUnavailable, order.Lines[i].LineNumber)
+		}
+		order.Lines[i].Tax = tax
+	}
+	if err := order.CheckAmounts(order.Totals.Shipping); err != nil {
+		return fmt.Errorf("%w: %v", ErrTaxUnavailable, err)
+	}
+	order.ComputeTotals(order.Totals.Shipping)
+	return nil
+}
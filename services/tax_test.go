@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/models"
+)
+
+// fakeTaxSettings returns fixed settings, or err, for every tenant.
+type fakeTaxSettings struct {
+	settings *models.TenantSettings
+	err      error
+}
+
+func (f fakeTaxSettings) Get(ctx context.Context, tenantID int) (*models.TenantSettings, error) {
+	return f.settings, f.err
+}
+
+// fakeTaxCalculator returns fixed taxes, or err, and keeps the last request.
+type fakeTaxCalculator struct {
+	taxes map[int]models.Money
+	err   error
+	req   *TaxRequest
+}
+
+func (f *fakeTaxCalculator) Calculate(ctx context.Context, req TaxRequest) (map[int]models.Money, error) {
+	f.req = &req
+	return f.taxes, f.err
+}
+
+func TestRuleTaxCalculator(t *testing.T) {
+	calc := RuleTaxCalculator{Rules: []models.TaxRule{
+		{CountryCode: "AE", Rate: 0.05},
+		{CountryCode: "AE", Category: "food", Rate: 0},
+	}}
+	lines := []TaxRequestLine{
+		{LineNumber: 1, Amount: 10000},
+		{LineNumber: 2, TaxCategory: "food", Amount: 5000},
+		{LineNumber: 3, Amount: 999},
+	}
+
+	tests := []struct {
+		name    string
+		address *models.Address
+		want    map[int]models.Money
+	}{
+		{
+			name:    "matching rules",
+			address: &models.Address{CountryCode: "AE"},
+			want:    map[int]models.Money{1: 500, 2: 0, 3: 50},
+		},
+		{
+			name:    "no rule for the country",
+			address: &models.Address{CountryCode: "GB"},
+			want:    map[int]models.Money{1: 0, 2: 0, 3: 0},
+		},
+		{
+			name: "no shipping address",
+			want: map[int]models.Money{1: 0, 2: 0, 3: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.Calculate(context.Background(), TaxRequest{ShippingAddress: tt.address, Lines: lines})
+			if err != nil {
+				t.Fatalf("Calculate: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Calculate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPTaxCalculator(t *testing.T) {
+	req := TaxRequest{
+		TenantID: 7,
+		OrderID:  "ORD-1",
+		Currency: "USD",
+		Lines:    []TaxRequestLine{{LineNumber: 1, SKUID: "sku-a", Qty: 2, Amount: 2000}},
+	}
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		delay   time.Duration
+		want    map[int]models.Money
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			body:   `{"lines": [{"line_number": 1, "tax": 160}]}`,
+			want:   map[int]models.Money{1: 160},
+		},
+		{name: "error status", status: http.StatusInternalServerError, body: `{"error": "down"}`, wantErr: true},
+		{name: "negative tax", status: http.StatusOK, body: `{"lines": [{"line_number": 1, "tax": -5}]}`, wantErr: true},
+		{name: "malformed response", status: http.StatusOK, body: `not json`, wantErr: true},
+		{name: "timeout", status: http.StatusOK, body: `{"lines": []}`, delay: 200 * time.Millisecond, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got TaxRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("method = %s, want POST", r.Method)
+				}
+				if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+					t.Errorf("Authorization = %q, want %q", auth, "Bearer secret")
+				}
+				if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", ct)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+				if tt.delay > 0 {
+					select {
+					case <-time.After(tt.delay):
+					case <-r.Context().Done():
+						return
+					}
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+
+			calc := &HTTPTaxCalculator{
+				URL:        srv.URL,
+				AuthToken:  "secret",
+				HTTPClient: &http.Client{Timeout: 50 * time.Millisecond},
+			}
+			taxes, err := calc.Calculate(context.Background(), req)
+			srv.Close()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Calculate error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(taxes, tt.want) {
+				t.Errorf("Calculate = %v, want %v", taxes, tt.want)
+			}
+			if !reflect.DeepEqual(got, req) {
+				t.Errorf("provider got %+v, want %+v", got, req)
+			}
+		})
+	}
+}
+
+func TestTaxServiceApply(t *testing.T) {
+	newOrder := func() *models.Order {
+		order := &models.Order{
+			OrderID:         "ORD-1",
+			CustomerID:      7,
+			Currency:        "AED",
+			ShippingAddress: &models.Address{CountryCode: "AE"},
+			Lines: []models.OrderLine{
+				{LineNumber: 1, SKUID: "sku-a", Qty: 2, UnitPrice: 5000, Discount: 1000, Tax: 1},
+				{LineNumber: 2, SKUID: "sku-b", TaxCategory: "food", Qty: 1, UnitPrice: 3000, Tax: 1},
+			},
+		}
+		order.ComputeTotals(500)
+		return order
+	}
+	rules := []models.TaxRule{{CountryCode: "AE", Rate: 0.05}, {CountryCode: "AE", Category: "food", Rate: 0}}
+
+	tests := []struct {
+		name      string
+		settings  fakeTaxSettings
+		external  *fakeTaxCalculator
+		unpriced  bool
+		wantTaxes []models.Money
+		wantErr   error
+	}{
+		{
+			name:      "no settings keeps submitted tax",
+			wantTaxes: []models.Money{1, 1},
+		},
+		{
+			name:      "submitted provider",
+			settings:  fakeTaxSettings{settings: &models.TenantSettings{TaxProvider: models.TaxProviderSubmitted, TaxRules: rules}},
+			wantTaxes: []models.Money{1, 1},
+		},
+		{
+			name:      "unpriced order is left alone",
+			settings:  fakeTaxSettings{settings: &models.TenantSettings{TaxProvider: models.TaxProviderRules, TaxRules: rules}},
+			unpriced:  true,
+			wantTaxes: []models.Money{1, 1},
+		},
+		{
+			name:      "tenant rules",
+			settings:  fakeTaxSettings{settings: &models.TenantSettings{TaxProvider: models.TaxProviderRules, TaxRules: rules}},
+			wantTaxes: []models.Money{450, 0},
+		},
+		{
+			name:      "external provider",
+			settings:  fakeTaxSettings{settings: &models.TenantSettings{TaxProvider: models.TaxProviderExternal}},
+			external:  &fakeTaxCalculator{taxes: map[int]models.Money{1: 700, 2: 300}},
+			wantTaxes: []models.Money{700, 300},
+		},
+		{
+			name:     "external provider not configured",
+			settings: fakeTaxSettings{settings: &models.TenantSettings{TaxProvider: models.TaxProviderExternal}},
+			wantErr:  ErrTaxUnavailable,
+		},
+		{
+			name:     "external provider fails",
+			settings: fakeTaxSettings{settings: &models.TenantSettings{TaxProvider: models.TaxProviderExternal}},
+			external: &fakeTaxCalculator{err: errors.New("timeout")},
+			wantErr:  ErrTaxUnavailable,
+		},
+		{
+			name:     "external provider skips a line",
+			settings: fakeTaxSettings{settings: &models.TenantSettings{TaxProvider: models.TaxProviderExternal}},
+			external: &fakeTaxCalculator{taxes: map[int]models.Money{1: 700}},
+			wantErr:  ErrTaxUnavailable,
+		},
+		{
+			name:     "settings lookup fails",
+			settings: fakeTaxSettings{err: errors.New("mongo down")},
+			wantErr:  ErrTaxUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &TaxService{Settings: tt.settings}
+			if tt.external != nil {
+				s.External = tt.external
+			}
+			order := newOrder()
+			if tt.unpriced {
+				order.Totals = nil
+			}
+			before := order.Totals
+
+			err := s.Apply(context.Background(), order)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			got := []models.Money{order.Lines[0].Tax, order.Lines[1].Tax}
+			if !reflect.DeepEqual(got, tt.wantTaxes) {
+				t.Errorf("line taxes = %v, want %v", got, tt.wantTaxes)
+			}
+			if tt.unpriced {
+				if order.Totals != nil {
+					t.Errorf("totals = %+v, want none", order.Totals)
+				}
+				return
+			}
+			wantTotals := models.OrderTotals{
+				Subtotal: 13000,
+				Discount: 1000,
+				Shipping: before.Shipping,
+				Tax:      got[0] + got[1],
+			}
+			wantTotals.GrandTotal = wantTotals.Subtotal - wantTotals.Discount + wantTotals.Shipping + wantTotals.Tax
+			if *order.Totals != wantTotals {
+				t.Errorf("totals = %+v, want %+v", *order.Totals, wantTotals)
+			}
+		})
+	}
+}
+
+func TestTaxServiceApplyRequest(t *testing.T) {
+	external := &fakeTaxCalculator{taxes: map[int]models.Money{1: 0}}
+	s := &TaxService{
+		Settings: fakeTaxSettings{settings: &models.TenantSettings{TaxProvider: models.TaxProviderExternal}},
+		External: external,
+	}
+	order := &models.Order{
+		OrderID:         "ORD-1",
+		CustomerID:      7,
+		Currency:        "USD",
+		ShippingAddress: &models.Address{State: "CA", CountryCode: "US"},
+		Lines:           []models.OrderLine{{LineNumber: 1, SKUID: "sku-a", TaxCategory: "books", Qty: 3, UnitPrice: 1000, Discount: 250}},
+	}
+	order.ComputeTotals(0)
+
+	if err := s.Apply(context.Background(), order); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	want := TaxRequest{
+		TenantID:        7,
+		OrderID:         "ORD-1",
+		Currency:        "USD",
+		ShippingAddress: order.ShippingAddress,
+		Lines:           []TaxRequestLine{{LineNumber: 1, SKUID: "sku-a", TaxCategory: "books", Qty: 3, Amount: 2750}},
+	}
+	if external.req == nil || !reflect.DeepEqual(*external.req, want) {
+		t.Errorf("tax request = %+v, want %+v", external.req, want)
+	}
+}
+
+func TestTaxServiceApplyProviderTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	s := &TaxService{
+		Settings: fakeTaxSettings{settings: &models.TenantSettings{TaxProvider: models.TaxProviderExternal}},
+		External: &HTTPTaxCalculator{URL: srv.URL, HTTPClient: &http.Client{Timeout: 20 * time.Millisecond}},
+	}
+	order := &models.Order{Lines: []models.OrderLine{{LineNumber: 1, Qty: 1, UnitPrice: 1000, Tax: 80}}}
+	order.ComputeTotals(0)
+	before := *order.Totals
+
+	if err := s.Apply(context.Background(), order); !errors.Is(err, ErrTaxUnavailable) {
+		t.Fatalf("Apply error = %v, want %v", err, ErrTaxUnavailable)
+	}
+	if order.Lines[0].Tax != 80 || *order.Totals != before {
+		t.Errorf("order changed after a failed tax lookup: tax %d, totals %+v", order.Lines[0].Tax, *order.Totals)
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RohitGupta-omniful/OMS/db"
+	"github.com/RohitGupta-omniful/OMS/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TenantSettingsService struct{}
+
+// NewTenantSettingsService creates and returns a new TenantSettingsService instance.
+func NewTenantSettingsService() *TenantSettingsService {
+	return &TenantSettingsService{}
+}
+
+// Get returns a tenant's settings, or nil when the tenant has none.
+func (s *TenantSettingsService) Get(ctx context.Context, tenantID int) (*models.TenantSettings, error) {
+	var settings models.TenantSettings
+	err := db.TenantSettingsCollection().FindOne(ctx, bson.M{"tenant_id": tenantID}).Decode(&settings)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Update applies the non-nil fields of update to a tenant's settings,
+// creating them if needed. New settings start from the default fulfilment
+// policy.
+func (s *TenantSettingsService) Update(ctx context.Context, tenantID int, update models.TenantSettingsUpdate) (*models.TenantSettings, error) {
+	set := bson.M{"updated_at": time.Now().UTC()}
+	setOnInsert := bson.M{}
+	if update.FulfilmentPolicy != nil {
+		set["fulfilment_policy"] = *update.FulfilmentPolicy
+	} else {
+		setOnInsert["fulfilment_policy"] = models.DefaultFulfilmentPolicy
+	}
+	if update.HubPriority != nil {
+		set["hub_priority"] = *update.HubPriority
+	}
+	if update.AllocationStrategies != nil {
+		set["allocation_strategies"] = *update.AllocationStrategies
+	}
+	if update.SplitOrders != nil {
+		set["split_orders"] = *update.SplitOrders
+	}
+	if update.TaxProvider != nil {
+		set["tax_provider"] = *update.TaxProvider
+	}
+	if update.TaxRules != nil {
+		set["tax_rules"] = *update.TaxRules
+	}
+
+	change := bson.M{"$set": set}
+	if len(setOnInsert) > 0 {
+		change["$setOnInsert"] = setOnInsert
+	}
+
+	var settings models.TenantSettings
+	err := db.TenantSettingsCollection().FindOneAndUpdate(
+		ctx,
+		bson.M{"tenant_id": tenantID},
+		change,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&settings)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}